@@ -0,0 +1,461 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"freescholar-backend/config"
+	"freescholar-backend/internal/indexer"
+	"freescholar-backend/internal/models"
+	"freescholar-backend/pkg/elasticsearch"
+
+	"github.com/gin-gonic/gin"
+	"github.com/olivere/elastic/v7"
+	"gorm.io/gorm"
+)
+
+// AuthorHandler handles HTTP requests related to authors
+type AuthorHandler struct {
+	db       *gorm.DB
+	esClient *elasticsearch.Client
+	config   *config.Config
+}
+
+// NewAuthorHandler creates a new author handler
+func NewAuthorHandler(db *gorm.DB, esClient *elasticsearch.Client, cfg *config.Config) *AuthorHandler {
+	return &AuthorHandler{
+		db:       db,
+		esClient: esClient,
+		config:   cfg,
+	}
+}
+
+// AuthorInput represents input for creating/updating authors
+type AuthorInput struct {
+	Name        string `json:"name" binding:"required"`
+	Institution string `json:"institution"`
+	Email       string `json:"email"`
+	WebsiteURL  string `json:"website_url"`
+	Biography   string `json:"biography"`
+}
+
+// AuthorMergeInput represents input for merging duplicate authors into one
+type AuthorMergeInput struct {
+	DuplicateIDs []uint `json:"duplicate_ids" binding:"required,min=1"`
+}
+
+// GetAuthors handles fetching multiple authors with name search and pagination
+func (h *AuthorHandler) GetAuthors(c *gin.Context) {
+	// Parse query parameters
+	query := c.Query("q")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	// Ensure reasonable pagination values
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	// If search query is provided, use Elasticsearch
+	if query != "" {
+		// Match on name, tolerating typos and transliteration differences
+		esQuery := elastic.NewMultiMatchQuery(query,
+			"name^3",
+			"institution",
+		).Type("best_fields").Fuzziness("AUTO")
+
+		searchResult, err := h.esClient.Search().
+			Index(indexer.AuthorIndex).
+			Query(esQuery).
+			From(offset).
+			Size(limit).
+			Do(context.Background())
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Search error"})
+			return
+		}
+
+		// Process search results
+		var authors []models.AuthorSearch
+		var total = searchResult.TotalHits()
+
+		for _, hit := range searchResult.Hits.Hits {
+			var author models.AuthorSearch
+			if err := json.Unmarshal(hit.Source, &author); err != nil {
+				continue
+			}
+			authors = append(authors, author)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"authors": authors,
+			"total":   total,
+			"page":    page,
+			"limit":   limit,
+			"pages":   (total + int64(limit) - 1) / int64(limit),
+		})
+		return
+	}
+
+	// Otherwise, use database query
+	var authors []models.Author
+	var total int64
+
+	db := h.db.Model(&models.Author{})
+
+	// Filter by institution if provided
+	if institution := c.Query("institution"); institution != "" {
+		db = db.Where("institution LIKE ?", "%"+institution+"%")
+	}
+
+	// Get total count
+	db.Count(&total)
+
+	// Get paginated results
+	err := db.Offset(offset).
+		Limit(limit).
+		Order("name ASC").
+		Find(&authors).Error
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch authors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"authors": authors,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+		"pages":   (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// GetAuthor handles fetching a single author by ID with their publications
+func (h *AuthorHandler) GetAuthor(c *gin.Context) {
+	id := c.Param("id")
+
+	var author models.Author
+	err := h.db.Preload("Publications").First(&author, id).Error
+
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"author": author})
+}
+
+// CreateAuthor handles creating a new author
+func (h *AuthorHandler) CreateAuthor(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	_, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input AuthorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	author := models.Author{
+		Name:        input.Name,
+		Institution: input.Institution,
+		Email:       input.Email,
+		WebsiteURL:  input.WebsiteURL,
+		Biography:   input.Biography,
+	}
+
+	// Create the author and schedule indexing in one transaction
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&author).Error; err != nil {
+			return err
+		}
+		return indexer.IndexAuthor(tx, author.ID)
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create author"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Author created successfully",
+		"author":  author,
+	})
+}
+
+// UpdateAuthor handles updating an existing author
+func (h *AuthorHandler) UpdateAuthor(c *gin.Context) {
+	id := c.Param("id")
+
+	// Get user ID from context (set by auth middleware)
+	_, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Check if author exists
+	var author models.Author
+	if err := h.db.First(&author, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+
+	var input AuthorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	nameChanged := input.Name != author.Name
+
+	updates := map[string]interface{}{
+		"name":        input.Name,
+		"institution": input.Institution,
+		"email":       input.Email,
+		"website_url": input.WebsiteURL,
+		"biography":   input.Biography,
+	}
+
+	// Update the author and schedule re-indexing, including publications that carry the author's name
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&author).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := indexer.IndexAuthor(tx, author.ID); err != nil {
+			return err
+		}
+		if !nameChanged {
+			return nil
+		}
+
+		var publicationIDs []uint
+		if err := tx.Model(&models.PublicationAuthor{}).Where("author_id = ?", author.ID).Pluck("publication_id", &publicationIDs).Error; err != nil {
+			return err
+		}
+		return indexer.IndexPublications(tx, publicationIDs...)
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update author"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Author updated successfully",
+		"author":  author,
+	})
+}
+
+// DeleteAuthor handles deleting an author that is not linked to any publication
+func (h *AuthorHandler) DeleteAuthor(c *gin.Context) {
+	id := c.Param("id")
+
+	// Get user ID from context (set by auth middleware)
+	_, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Check if author exists
+	var author models.Author
+	if err := h.db.First(&author, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+
+	// Refuse to orphan publications; duplicates should be merged instead
+	var linked int64
+	h.db.Model(&models.PublicationAuthor{}).Where("author_id = ?", author.ID).Count(&linked)
+	if linked > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Author is linked to publications; merge it into another author instead"})
+		return
+	}
+
+	// Delete the author and schedule removal from Elasticsearch
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&author).Error; err != nil {
+			return err
+		}
+		return indexer.DeleteAuthor(tx, author.ID)
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete author"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Author deleted successfully",
+	})
+}
+
+// MergeAuthors moves all publications of the duplicate authors onto the canonical
+// author identified by the URL and deletes the duplicates
+func (h *AuthorHandler) MergeAuthors(c *gin.Context) {
+	id := c.Param("id")
+
+	// Get user ID from context (set by auth middleware)
+	_, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input AuthorMergeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check if canonical author exists
+	var canonical models.Author
+	if err := h.db.First(&canonical, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+
+	// Load duplicates, rejecting self-merges and unknown IDs
+	var duplicates []models.Author
+	for _, duplicateID := range input.DuplicateIDs {
+		if duplicateID == canonical.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge an author into itself"})
+			return
+		}
+
+		var duplicate models.Author
+		if err := h.db.First(&duplicate, duplicateID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Author not found: " + strconv.Itoa(int(duplicateID))})
+			return
+		}
+		duplicates = append(duplicates, duplicate)
+	}
+
+	// Start a transaction
+	tx := h.db.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	var publicationIDs []uint
+	for _, duplicate := range duplicates {
+		moved, err := mergeAuthorInto(tx, duplicate.ID, canonical.ID)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move publications"})
+			return
+		}
+		publicationIDs = append(publicationIDs, moved...)
+
+		// Keep details the canonical record is missing
+		fillAuthorDetails(&canonical, duplicate)
+
+		if err := tx.Delete(&duplicate).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete duplicate author"})
+			return
+		}
+
+		if err := indexer.DeleteAuthor(tx, duplicate.ID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule indexing"})
+			return
+		}
+	}
+
+	if err := tx.Save(&canonical).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update author"})
+		return
+	}
+
+	// Schedule re-indexing of the canonical author and the affected publications
+	if err := indexer.IndexAuthor(tx, canonical.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule indexing"})
+		return
+	}
+
+	if err := indexer.IndexPublications(tx, publicationIDs...); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule indexing"})
+		return
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Authors merged successfully",
+		"author":       canonical,
+		"publications": len(publicationIDs),
+	})
+}
+
+// mergeAuthorInto re-points every PublicationAuthor row of the duplicate author to
+// the canonical author and returns the IDs of the affected publications. Rows for
+// publications the canonical author is already linked to are dropped so a paper
+// never lists the same author twice.
+func mergeAuthorInto(tx *gorm.DB, duplicateID, canonicalID uint) ([]uint, error) {
+	var rows []models.PublicationAuthor
+	if err := tx.Where("author_id = ?", duplicateID).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var publicationIDs []uint
+	for _, row := range rows {
+		var existing int64
+		if err := tx.Model(&models.PublicationAuthor{}).
+			Where("publication_id = ? AND author_id = ?", row.PublicationID, canonicalID).
+			Count(&existing).Error; err != nil {
+			return nil, err
+		}
+
+		if existing > 0 {
+			// The join table doubles as the many2many table, so remove the row for good
+			if err := tx.Unscoped().Delete(&row).Error; err != nil {
+				return nil, err
+			}
+		} else if err := tx.Model(&row).Update("author_id", canonicalID).Error; err != nil {
+			return nil, err
+		}
+
+		publicationIDs = append(publicationIDs, row.PublicationID)
+	}
+
+	return publicationIDs, nil
+}
+
+// fillAuthorDetails copies non-empty fields of the duplicate onto blank fields of the canonical author
+func fillAuthorDetails(canonical *models.Author, duplicate models.Author) {
+	if canonical.Institution == "" {
+		canonical.Institution = duplicate.Institution
+	}
+	if canonical.Email == "" {
+		canonical.Email = duplicate.Email
+	}
+	if canonical.WebsiteURL == "" {
+		canonical.WebsiteURL = duplicate.WebsiteURL
+	}
+	if canonical.Biography == "" {
+		canonical.Biography = duplicate.Biography
+	}
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"freescholar-backend/config"
+	"freescholar-backend/internal/bibliography"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// BibliographyHandler handles importing and exporting publications in reference formats
+type BibliographyHandler struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+// NewBibliographyHandler creates a new bibliography handler
+func NewBibliographyHandler(db *gorm.DB, cfg *config.Config) *BibliographyHandler {
+	return &BibliographyHandler{
+		db:     db,
+		config: cfg,
+	}
+}
+
+// errDocumentTooLarge is returned when an uploaded document exceeds the import size limit
+var errDocumentTooLarge = errors.New("Document too large")
+
+// ImportPublications handles bulk-creating publications from an uploaded
+// document, given as the request body or as the "file" field of a form
+func (h *BibliographyHandler) ImportPublications(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	document, filename, err := h.readDocument(c)
+	if err != nil {
+		if errors.Is(err, errDocumentTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Document exceeds " + strconv.FormatInt(h.config.Import.MaxSize, 10) + " bytes"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format, err := importFormat(c, filename)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	records, failed := format.Parse(string(document))
+
+	entries := len(records) + len(failed)
+	if entries == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No entries found"})
+		return
+	}
+	if entries > h.config.Import.MaxEntries {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many entries; at most " + strconv.Itoa(h.config.Import.MaxEntries) + " per import"})
+		return
+	}
+
+	results := bibliography.Import(h.db, userID.(uint), records, failed)
+
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":    results,
+		"created":    counts[bibliography.StatusCreated],
+		"duplicates": counts[bibliography.StatusDuplicate],
+		"errors":     counts[bibliography.StatusError],
+	})
+}
+
+// GetPublicationBibTeX handles exporting a single publication as BibTeX
+func (h *BibliographyHandler) GetPublicationBibTeX(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found"})
+		return
+	}
+
+	records, err := bibliography.Load(h.db, []uint{uint(id)})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch publication"})
+		return
+	}
+	if len(records) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found"})
+		return
+	}
+
+	format, _ := bibliography.LookupFormat("bibtex")
+	writeRecords(c, format, records, "")
+}
+
+// CitePublication handles formatting the citation of a publication. Without
+// a style it lists all styles; with output=text or output=html it responds
+// with the bare citation of one style.
+func (h *BibliographyHandler) CitePublication(c *gin.Context) {
+	styles := bibliography.Styles
+	if value := c.Query("style"); value != "" {
+		styles = nil
+		for _, id := range strings.Split(value, ",") {
+			style, ok := bibliography.LookupStyle(strings.TrimSpace(id))
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported style: " + id})
+				return
+			}
+			styles = append(styles, style)
+		}
+	}
+
+	output := c.DefaultQuery("output", "json")
+	if output != "json" && output != "text" && output != "html" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "output must be json, text or html"})
+		return
+	}
+	if output != "json" && len(styles) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "output=" + output + " needs exactly one style"})
+		return
+	}
+
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	records, err := bibliography.Load(h.db, []uint{uint(id)})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch publication"})
+		return
+	}
+	if len(records) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found"})
+		return
+	}
+
+	citations := make([]bibliography.Citation, len(styles))
+	for i, style := range styles {
+		citations[i] = style.Cite(records[0])
+	}
+
+	switch output {
+	case "text":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(citations[0].Text))
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(citations[0].HTML))
+	default:
+		c.JSON(http.StatusOK, gin.H{"citations": citations})
+	}
+}
+
+// ExportPublications handles exporting several publications, given as ids=1,2,3
+func (h *BibliographyHandler) ExportPublications(c *gin.Context) {
+	var ids []uint
+	seen := make(map[uint]bool)
+	for _, part := range strings.Split(c.Query("ids"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid publication ID: " + part})
+			return
+		}
+		if !seen[uint(id)] {
+			seen[uint(id)] = true
+			ids = append(ids, uint(id))
+		}
+	}
+
+	if len(ids) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids is required"})
+		return
+	}
+	if len(ids) > h.config.Import.ExportMaxIDs {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many publications; at most " + strconv.Itoa(h.config.Import.ExportMaxIDs) + " per export"})
+		return
+	}
+
+	// Exports default to BibTeX when neither format nor Accept names a format
+	format, ok, err := requestedFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		format, _ = bibliography.LookupFormat("bibtex")
+	}
+
+	records, err := bibliography.Load(h.db, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch publications"})
+		return
+	}
+
+	writeRecords(c, format, records, "publications"+format.Extension)
+}
+
+// requestedFormat returns the reference format named by the format query
+// parameter or the Accept header. ok is false when JSON was asked for or
+// no format was named.
+func requestedFormat(c *gin.Context) (format bibliography.Format, ok bool, err error) {
+	if name := c.Query("format"); name != "" {
+		if name == "json" {
+			return format, false, nil
+		}
+		format, ok = bibliography.LookupFormat(name)
+		if !ok {
+			return format, false, errors.New("Unsupported format: " + name)
+		}
+		return format, true, nil
+	}
+
+	format, ok = bibliography.NegotiateFormat(c.GetHeader("Accept"))
+	return format, ok, nil
+}
+
+// importFormat returns the format of an uploaded document, named by the
+// format query parameter or else by the content type or file extension.
+// Documents of unknown type are read as BibTeX.
+func importFormat(c *gin.Context, filename string) (bibliography.Format, error) {
+	if name := c.Query("format"); name != "" {
+		format, ok := bibliography.LookupFormat(name)
+		if !ok {
+			return format, errors.New("Unsupported format: " + name)
+		}
+		return format, nil
+	}
+
+	if format, ok := bibliography.NegotiateFormat(c.ContentType()); ok {
+		return format, nil
+	}
+	if c.ContentType() == "application/json" {
+		// The only JSON format is CSL-JSON
+		format, _ := bibliography.LookupFormat("csl-json")
+		return format, nil
+	}
+	for _, format := range bibliography.Formats {
+		if filename != "" && strings.HasSuffix(strings.ToLower(filename), format.Extension) {
+			return format, nil
+		}
+	}
+
+	format, _ := bibliography.LookupFormat("bibtex")
+	return format, nil
+}
+
+// writeRecords responds with records in a reference format, as a download if filename is set
+func writeRecords(c *gin.Context, format bibliography.Format, records []bibliography.Record, filename string) {
+	var buf bytes.Buffer
+	if err := format.Write(&buf, records); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write " + format.Name})
+		return
+	}
+
+	if filename != "" {
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	}
+	c.Data(http.StatusOK, format.MediaType+"; charset=utf-8", buf.Bytes())
+}
+
+// readDocument reads an uploaded document from the "file" form field or the
+// request body and returns it with the name of the uploaded file
+func (h *BibliographyHandler) readDocument(c *gin.Context) ([]byte, string, error) {
+	limit := h.config.Import.MaxSize
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+1<<20)
+
+	var reader io.Reader = c.Request.Body
+	var filename string
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			var maxBytes *http.MaxBytesError
+			if errors.As(err, &maxBytes) {
+				return nil, "", errDocumentTooLarge
+			}
+			return nil, "", errors.New("file is required")
+		}
+		if header.Size > limit {
+			return nil, "", errDocumentTooLarge
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		defer file.Close()
+		reader = file
+		filename = header.Filename
+	}
+
+	document, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			return nil, "", errDocumentTooLarge
+		}
+		return nil, "", err
+	}
+	if int64(len(document)) > limit {
+		return nil, "", errDocumentTooLarge
+	}
+	return document, filename, nil
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"freescholar-backend/config"
+	"freescholar-backend/internal/indexer"
+	"freescholar-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CitationHandler handles HTTP requests related to the citation graph
+type CitationHandler struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+// NewCitationHandler creates a new citation handler
+func NewCitationHandler(db *gorm.DB, cfg *config.Config) *CitationHandler {
+	return &CitationHandler{
+		db:     db,
+		config: cfg,
+	}
+}
+
+// errReferenceNotFound is returned when a reference names a cited_id that does not exist
+var errReferenceNotFound = errors.New("Referenced publication not found")
+
+// ReferenceInput is one entry of a reference list. A reference is resolved
+// by cited_id, then by doi, then by a DOI found in raw_reference; references
+// that match nothing are kept unresolved.
+type ReferenceInput struct {
+	CitedID      *uint  `json:"cited_id"`
+	DOI          string `json:"doi"`
+	RawReference string `json:"raw_reference"`
+}
+
+// ReferencesInput represents input for replacing a reference list
+type ReferencesInput struct {
+	References []ReferenceInput `json:"references"`
+}
+
+// GetReferences handles listing the reference list of a publication
+func (h *CitationHandler) GetReferences(c *gin.Context) {
+	var publication models.Publication
+	if err := h.db.First(&publication, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found"})
+		return
+	}
+
+	page, limit := citationPagination(c)
+
+	db := h.db.Model(&models.Citation{}).Where("citing_id = ?", publication.ID)
+
+	var total int64
+	db.Count(&total)
+
+	var references []models.Citation
+	err := db.Preload("Cited").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Order("position ASC").
+		Find(&references).Error
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch references"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"references": references,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"pages":      (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// GetCitedBy handles listing the publications that cite a publication
+func (h *CitationHandler) GetCitedBy(c *gin.Context) {
+	var publication models.Publication
+	if err := h.db.First(&publication, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found"})
+		return
+	}
+
+	page, limit := citationPagination(c)
+
+	citing := h.db.Model(&models.Citation{}).
+		Select("citing_id").
+		Where("cited_id = ?", publication.ID)
+	db := h.db.Model(&models.Publication{}).Where("id IN (?)", citing)
+
+	var total int64
+	db.Count(&total)
+
+	var publications []models.Publication
+	err := db.Offset((page - 1) * limit).
+		Limit(limit).
+		Order("publication_date DESC").
+		Find(&publications).Error
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch citing publications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"publications": publications,
+		"total":        total,
+		"page":         page,
+		"limit":        limit,
+		"pages":        (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// SetReferences handles replacing the reference list of a publication
+func (h *CitationHandler) SetReferences(c *gin.Context) {
+	var publication models.Publication
+	if err := h.db.First(&publication, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found"})
+		return
+	}
+
+	var input ReferencesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for i, reference := range input.References {
+		if reference.CitedID == nil && strings.TrimSpace(reference.DOI) == "" && strings.TrimSpace(reference.RawReference) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Reference " + strconv.Itoa(i) + " needs a cited_id, doi or raw_reference"})
+			return
+		}
+		if reference.CitedID != nil && *reference.CitedID == publication.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A publication cannot cite itself"})
+			return
+		}
+	}
+
+	var references []models.Citation
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		previous, err := models.CitedIDs(tx, publication.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("citing_id = ?", publication.ID).Delete(&models.Citation{}).Error; err != nil {
+			return err
+		}
+
+		for i, reference := range input.References {
+			citation, err := resolveReference(tx, publication.ID, reference)
+			if err != nil {
+				return err
+			}
+			citation.Position = i
+			if err := tx.Create(&citation).Error; err != nil {
+				return err
+			}
+			references = append(references, citation)
+		}
+
+		current, err := models.CitedIDs(tx, publication.ID)
+		if err != nil {
+			return err
+		}
+
+		// Counts change for publications added to or dropped from the list
+		affected := append(previous, current...)
+		if err := models.RecountCitations(tx, affected...); err != nil {
+			return err
+		}
+		return indexer.IndexPublications(tx, affected...)
+	})
+
+	if err != nil {
+		if errors.Is(err, errReferenceNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update references"})
+		return
+	}
+
+	resolved := 0
+	for _, reference := range references {
+		if reference.CitedID != nil {
+			resolved++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "References updated successfully",
+		"references": references,
+		"resolved":   resolved,
+		"unresolved": len(references) - resolved,
+	})
+}
+
+// resolveReference turns a reference into a citation, linking it to a known publication if possible
+func resolveReference(tx *gorm.DB, citingID uint, reference ReferenceInput) (models.Citation, error) {
+	citation := models.Citation{
+		CitingID:     citingID,
+		RawReference: strings.TrimSpace(reference.RawReference),
+		DOI:          models.ExtractDOI(reference.DOI),
+	}
+	if citation.DOI == "" {
+		citation.DOI = models.ExtractDOI(citation.RawReference)
+	}
+
+	if reference.CitedID != nil {
+		var cited models.Publication
+		if err := tx.First(&cited, *reference.CitedID).Error; err != nil {
+			return citation, errReferenceNotFound
+		}
+		citation.CitedID = &cited.ID
+		if citation.DOI == "" {
+			citation.DOI = strings.ToLower(string(cited.DOI))
+		}
+		return citation, nil
+	}
+
+	if citation.DOI != "" {
+		var cited models.Publication
+		result := tx.Where("doi = ? AND id <> ?", citation.DOI, citingID).Limit(1).Find(&cited)
+		if result.Error != nil {
+			return citation, result.Error
+		}
+		if result.RowsAffected > 0 {
+			citation.CitedID = &cited.ID
+		}
+	}
+
+	return citation, nil
+}
+
+// linkCitations resolves pending references to a publication by its DOI and
+// refreshes its citation count; call it after the DOI was set
+func linkCitations(tx *gorm.DB, publication models.Publication) error {
+	linked, err := models.ResolveCitations(tx, publication)
+	if err != nil || linked == 0 {
+		return err
+	}
+	return models.RecountCitations(tx, publication.ID)
+}
+
+func citationPagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	// Ensure reasonable pagination values
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	return page, limit
+}
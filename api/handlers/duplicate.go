@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"freescholar-backend/config"
+	"freescholar-backend/internal/dedup"
+	"freescholar-backend/internal/indexer"
+	"freescholar-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// duplicateCacheAge is how long clusters are reused while no publication changes
+const duplicateCacheAge = 10 * time.Minute
+
+// DuplicateHandler handles reviewing likely duplicate publications
+type DuplicateHandler struct {
+	db       *gorm.DB
+	config   *config.Config
+	clusters *dedup.Cache
+}
+
+// NewDuplicateHandler creates a new duplicate handler
+func NewDuplicateHandler(db *gorm.DB, cfg *config.Config) *DuplicateHandler {
+	return &DuplicateHandler{
+		db:       db,
+		config:   cfg,
+		clusters: dedup.NewCache(db, duplicateCacheAge),
+	}
+}
+
+// DuplicateCluster is a cluster of likely duplicates with the publications loaded
+type DuplicateCluster struct {
+	dedup.Cluster
+	Publications []models.PublicationSearch `json:"publications"`
+}
+
+// GetDuplicates handles listing clusters of likely duplicate publications,
+// to be resolved with POST /publication/:id/merge
+func (h *DuplicateHandler) GetDuplicates(c *gin.Context) {
+	threshold, err := strconv.ParseFloat(c.DefaultQuery("threshold", "0.85"), 64)
+	if err != nil || threshold < 0.5 || threshold > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "threshold must be between 0.5 and 1"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	// Ensure reasonable pagination values
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	clusters, err := h.clusters.Find(threshold)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find duplicates"})
+		return
+	}
+
+	total := int64(len(clusters))
+	start := min((page-1)*limit, len(clusters))
+	end := min(start+limit, len(clusters))
+	clusters = clusters[start:end]
+
+	// Load the publications of the page in one go
+	var ids []uint
+	for _, cluster := range clusters {
+		ids = append(ids, cluster.IDs...)
+	}
+	var publications []models.Publication
+	if err := h.db.Where("id IN ?", ids).Find(&publications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch publications"})
+		return
+	}
+	docs, err := indexer.LoadPublicationDocuments(h.db, publications)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch publications"})
+		return
+	}
+	byID := make(map[uint]models.PublicationSearch, len(docs))
+	for _, doc := range docs {
+		byID[doc.ID] = doc
+	}
+
+	items := make([]DuplicateCluster, len(clusters))
+	for i, cluster := range clusters {
+		items[i] = DuplicateCluster{Cluster: cluster, Publications: []models.PublicationSearch{}}
+		for _, id := range cluster.IDs {
+			if doc, ok := byID[id]; ok {
+				items[i].Publications = append(items[i].Publications, doc)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clusters": items,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+		"pages":    (total + int64(limit) - 1) / int64(limit),
+	})
+}
@@ -0,0 +1,475 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"freescholar-backend/config"
+	"freescholar-backend/internal/indexer"
+	"freescholar-backend/internal/media"
+	"freescholar-backend/internal/models"
+	"freescholar-backend/pkg/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// pdfMagic starts every PDF file
+var pdfMagic = []byte("%PDF-")
+
+// FilesHandler handles uploading and downloading files
+type FilesHandler struct {
+	db     *gorm.DB
+	store  *media.Store
+	config *config.Config
+}
+
+// NewFilesHandler creates a new files handler
+func NewFilesHandler(db *gorm.DB, store *media.Store, cfg *config.Config) *FilesHandler {
+	return &FilesHandler{
+		db:     db,
+		store:  store,
+		config: cfg,
+	}
+}
+
+// UploadPDF handles attaching a PDF, sent as the "file" field of a form, to
+// a publication. Uploading a PDF the publication already has returns the
+// existing file; content shared with other publications is stored once.
+func (h *FilesHandler) UploadPDF(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var publication models.Publication
+	if err := h.db.First(&publication, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found"})
+		return
+	}
+
+	limit := h.config.Media.MaxUploadSize
+	tooLarge := gin.H{"error": "File exceeds " + strconv.FormatInt(limit, 10) + " bytes"}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+1<<20)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if header.Size > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+
+	visibility := c.DefaultPostForm("visibility", models.VisibilityPublic)
+	var embargoUntil *time.Time
+	if value := c.PostForm("embargo_until"); value != "" {
+		date, err := parseEmbargoDate(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		embargoUntil = &date
+	}
+	embargoUntil, err = checkVisibility(visibility, embargoUntil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	upload, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer upload.Close()
+
+	// Trust the content, not the declared Content-Type or file name
+	reader := bufio.NewReader(upload)
+	if magic, _ := reader.Peek(len(pdfMagic)); !bytes.Equal(magic, pdfMagic) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "File is not a PDF"})
+		return
+	}
+
+	blob, err := h.store.Save(c.Request.Context(), "pdf", ".pdf", "application/pdf", reader, limit)
+	if err != nil {
+		if errors.Is(err, media.ErrTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+		return
+	}
+
+	var existing models.File
+	if h.db.Omit("text").Where("publication_id = ? AND checksum = ?", publication.ID, blob.Checksum).Limit(1).Find(&existing).RowsAffected > 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Publication already has this file",
+			"file":    existing,
+		})
+		return
+	}
+
+	file := models.File{
+		FileName:      uploadName(header.Filename, ".pdf"),
+		FilePath:      blob.Path,
+		FileSize:      blob.Size,
+		ContentType:   "application/pdf",
+		Checksum:      blob.Checksum,
+		TextStatus:    models.TextPending,
+		UploaderID:    userID.(uint),
+		PublicationID: &publication.ID,
+		Visibility:    visibility,
+		EmbargoUntil:  embargoUntil,
+	}
+
+	// Attach the file and schedule re-indexing, as the search document records whether there is a PDF
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&file).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&publication).UpdateColumn("pdf_path", file.FilePath).Error; err != nil {
+			return err
+		}
+		return indexer.IndexPublications(tx, publication.ID)
+	})
+
+	if err != nil {
+		if blob.Created {
+			h.store.Remove(c.Request.Context(), blob.Path)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to attach file"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "File uploaded successfully",
+		"file":    file,
+	})
+}
+
+// GetFile handles downloading a file by ID. Files that are not public need
+// a user who may edit their publication or a signed share link.
+func (h *FilesHandler) GetFile(c *gin.Context) {
+	var file models.File
+	if err := h.db.Omit("text").First(&file, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	// Files of deleted publications go with them
+	if file.PublicationID != nil {
+		var count int64
+		h.db.Model(&models.Publication{}).Where("id = ?", *file.PublicationID).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+	}
+
+	if !h.canRead(c, file) {
+		denyFile(c, file)
+		return
+	}
+
+	h.serveFile(c, file)
+}
+
+// GetPublicationPDF handles downloading the most recently uploaded PDF of a
+// publication that the user may read
+func (h *FilesHandler) GetPublicationPDF(c *gin.Context) {
+	var publication models.Publication
+	if err := h.db.First(&publication, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found"})
+		return
+	}
+
+	var files []models.File
+	if err := h.db.Omit("text").Where("publication_id = ?", publication.ID).Order("id DESC").Find(&files).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load files"})
+		return
+	}
+	if len(files) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication has no PDF"})
+		return
+	}
+
+	h.serveReadable(c, files)
+}
+
+// ServeMedia handles downloading a stored file by its path, as found in
+// the pdf_path of publications
+func (h *FilesHandler) ServeMedia(c *gin.Context) {
+	path := strings.TrimPrefix(c.Param("path"), "/")
+
+	// Identical content is stored once, so several files may share the path
+	var files []models.File
+	err := h.db.Omit("text").Where("file_path = ?", path).
+		Where("publication_id IS NULL OR publication_id IN (?)", h.db.Model(&models.Publication{}).Select("id")).
+		Order("id DESC").
+		Find(&files).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load files"})
+		return
+	}
+	if len(files) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	h.serveReadable(c, files)
+}
+
+// SetFileVisibility handles changing who may download a file
+func (h *FilesHandler) SetFileVisibility(c *gin.Context) {
+	var input models.FileVisibility
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	embargoUntil, err := checkVisibility(input.Visibility, input.EmbargoUntil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var file models.File
+	if err := h.db.Omit("text").First(&file, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	// Only public text is searchable, so the publication is re-indexed
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&file).Updates(map[string]interface{}{
+			"visibility":    input.Visibility,
+			"embargo_until": embargoUntil,
+		}).Error
+		if err != nil || file.PublicationID == nil {
+			return err
+		}
+		return indexer.IndexPublications(tx, *file.PublicationID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update file"})
+		return
+	}
+	file.Visibility = input.Visibility
+	file.EmbargoUntil = embargoUntil
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "File visibility updated successfully",
+		"file":    file,
+	})
+}
+
+// ShareFile handles creating a link that downloads a file without logging
+// in until it expires, whatever the file's visibility
+func (h *FilesHandler) ShareFile(c *gin.Context) {
+	var input models.FileShare
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var file models.File
+	if err := h.db.Omit("text").First(&file, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	ttl := h.config.Media.ShareTTL
+	if input.ExpiresIn > 0 {
+		ttl = input.ExpiresIn
+	}
+	if ttl <= 0 {
+		ttl = 24 * 60 * 60
+	}
+	if maxTTL := h.config.Media.ShareMaxTTL; maxTTL > 0 && ttl > maxTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in may be at most " + strconv.Itoa(maxTTL) + " seconds"})
+		return
+	}
+
+	expires := time.Now().Add(time.Duration(ttl) * time.Second).Truncate(time.Second)
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("signature", media.ShareSignature(h.signingKey(), file.ID, expires))
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":        "/api/media/" + strconv.FormatUint(uint64(file.ID), 10) + "?" + query.Encode(),
+		"expires_at": expires,
+	})
+}
+
+// serveReadable serves the first of the files the user may read
+func (h *FilesHandler) serveReadable(c *gin.Context, files []models.File) {
+	for _, file := range files {
+		if h.canRead(c, file) {
+			h.serveFile(c, file)
+			return
+		}
+	}
+	denyFile(c, files[0])
+}
+
+// canRead reports whether the request may download a file: anyone may read
+// public files, share links grant access to one file until they expire, and
+// otherwise the uploader and users who may edit the publication have access
+func (h *FilesHandler) canRead(c *gin.Context, file models.File) bool {
+	now := time.Now()
+	if file.IsPublic(now) {
+		return true
+	}
+	if media.VerifyShare(h.signingKey(), file.ID, c.Query("expires"), c.Query("signature"), now) {
+		return true
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		return false
+	}
+	if file.UploaderID == userID.(uint) {
+		return true
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		return false
+	}
+	ownerID, err := models.FileOwnerID(h.db, file)
+	return err == nil && user.Can(models.ActionUpdate, ownerID)
+}
+
+// signingKey returns the key share links are signed with
+func (h *FilesHandler) signingKey() []byte {
+	if h.config.Media.SigningKey != "" {
+		return []byte(h.config.Media.SigningKey)
+	}
+	return []byte(h.config.JWT.Secret)
+}
+
+// denyFile responds to a request for a file the user may not read
+func denyFile(c *gin.Context, file models.File) {
+	if file.Visibility == models.VisibilityEmbargoed && file.EmbargoUntil != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":         "File is under embargo",
+			"embargo_until": file.EmbargoUntil,
+		})
+		return
+	}
+	if _, exists := c.Get("userID"); !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required to download this file"})
+		return
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this file"})
+}
+
+// serveFile streams a stored file, answering Range and conditional requests,
+// or redirects to a presigned URL if the storage driver hands them out.
+// Files are downloaded as attachments unless ?inline=true asks to display them.
+func (h *FilesHandler) serveFile(c *gin.Context, file models.File) {
+	disposition := "attachment"
+	if inline, _ := strconv.ParseBool(c.Query("inline")); inline {
+		disposition = "inline"
+	}
+	disposition = mime.FormatMediaType(disposition, map[string]string{"filename": file.FileName})
+
+	// Responses to files that are not public must not end up in shared caches
+	if !file.IsPublic(time.Now()) {
+		c.Header("Cache-Control", "private")
+	}
+
+	if ttl := h.config.Media.PresignTTL; ttl > 0 {
+		url, err := h.store.PresignGet(c.Request.Context(), file.FilePath, time.Duration(ttl)*time.Second, storage.ResponseHeaders{
+			ContentType:        file.ContentType,
+			ContentDisposition: disposition,
+		})
+		if err == nil {
+			c.Redirect(http.StatusFound, url)
+			return
+		}
+		if !errors.Is(err, storage.ErrPresignUnsupported) {
+			log.Printf("Failed to presign %s: %v", file.FilePath, err)
+		}
+	}
+
+	content, err := h.store.Open(c.Request.Context(), file.FilePath)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotExist) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer content.Close()
+
+	c.Header("Content-Type", file.ContentType)
+	c.Header("Content-Disposition", disposition)
+	c.Header("X-Content-Type-Options", "nosniff")
+	if file.Checksum != "" {
+		c.Header("ETag", `"`+file.Checksum+`"`)
+	}
+
+	http.ServeContent(c.Writer, c.Request, file.FileName, content.Info().ModTime, content)
+}
+
+// checkVisibility validates a file visibility, returning the embargo date
+// to store with it
+func checkVisibility(visibility string, embargoUntil *time.Time) (*time.Time, error) {
+	switch visibility {
+	case models.VisibilityPublic, models.VisibilityPrivate:
+		return nil, nil
+	case models.VisibilityEmbargoed:
+		if embargoUntil == nil {
+			return nil, errors.New("embargo_until is required for embargoed files")
+		}
+		return embargoUntil, nil
+	default:
+		return nil, fmt.Errorf("invalid visibility %q", visibility)
+	}
+}
+
+// parseEmbargoDate parses an embargo date given as a date or an RFC 3339 time
+func parseEmbargoDate(value string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid embargo_until %q", value)
+	}
+	return date, nil
+}
+
+// uploadName cleans the name of an uploaded file, giving it ext if it has another
+func uploadName(name, ext string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, `\`, "/")))
+	if name == "" || name == "." || name == "/" {
+		name = "document"
+	}
+	if !strings.EqualFold(filepath.Ext(name), ext) {
+		name += ext
+	}
+	return name
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"freescholar-backend/config"
+	"freescholar-backend/internal/bibliography"
+	"freescholar-backend/internal/metadata"
+	"freescholar-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MetadataHandler handles filling in publications from DOI metadata
+type MetadataHandler struct {
+	db       *gorm.DB
+	resolver metadata.Resolver
+	config   *config.Config
+}
+
+// NewMetadataHandler creates a new metadata handler
+func NewMetadataHandler(db *gorm.DB, resolver metadata.Resolver, cfg *config.Config) *MetadataHandler {
+	return &MetadataHandler{
+		db:       db,
+		resolver: resolver,
+		config:   cfg,
+	}
+}
+
+// DOIInput represents input for creating a publication from a DOI
+type DOIInput struct {
+	DOI string `json:"doi" binding:"required"`
+}
+
+// LookupDOI handles fetching the metadata of a DOI as a pre-filled PublicationInput.
+// It requires a login so anonymous clients cannot use up the Crossref quota.
+func (h *MetadataHandler) LookupDOI(c *gin.Context) {
+	doi := models.ExtractDOI(c.Query("doi"))
+	if doi == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid doi is required"})
+		return
+	}
+
+	record, ok := h.resolve(c, doi)
+	if !ok {
+		return
+	}
+
+	response := gin.H{"publication": publicationInput(record)}
+
+	// Let the client offer the existing publication instead of a duplicate
+	if existing, found, err := h.findByDOI(doi); err == nil && found {
+		response["existing_id"] = existing
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateFromDOI handles creating a publication from the metadata of a DOI
+func (h *MetadataHandler) CreateFromDOI(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input DOIInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doi := models.ExtractDOI(input.DOI)
+	if doi == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid doi is required"})
+		return
+	}
+
+	// Skip the lookup for DOIs we already have
+	existing, found, err := h.findByDOI(doi)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check for duplicates"})
+		return
+	}
+	if found {
+		c.JSON(http.StatusConflict, gin.H{"error": "A publication with this DOI already exists", "publication_id": existing})
+		return
+	}
+
+	record, ok := h.resolve(c, doi)
+	if !ok {
+		return
+	}
+	record.DOI = doi
+
+	id, err := bibliography.ImportRecord(h.db, userID.(uint), record)
+	if err != nil {
+		// A concurrent request may have won the unique index
+		if existing, found, _ := h.findByDOI(doi); found || errors.Is(err, bibliography.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A publication with this DOI already exists", "publication_id": existing})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create publication"})
+		return
+	}
+
+	var publication models.Publication
+	h.db.Preload("Authors").Preload("Keywords").First(&publication, id)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Publication created successfully",
+		"publication": publication,
+	})
+}
+
+// resolve looks up a DOI, responding with an error if that fails
+func (h *MetadataHandler) resolve(c *gin.Context, doi string) (bibliography.Record, bool) {
+	record, err := h.resolver.Resolve(c.Request.Context(), doi)
+	if err != nil {
+		if errors.Is(err, metadata.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "DOI not found"})
+			return record, false
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Metadata lookup failed"})
+		return record, false
+	}
+	if record.Title == "" || record.Year == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "The DOI's metadata lacks a title or date"})
+		return record, false
+	}
+	return record, true
+}
+
+// findByDOI returns the ID of the publication with a DOI
+func (h *MetadataHandler) findByDOI(doi string) (uint, bool, error) {
+	var publication models.Publication
+	// Trashed publications keep their DOI until they are purged
+	result := h.db.Unscoped().Where("doi = ?", doi).Limit(1).Find(&publication)
+	return publication.ID, result.RowsAffected > 0, result.Error
+}
+
+// publicationInput pre-fills a PublicationInput from a record
+func publicationInput(record bibliography.Record) PublicationInput {
+	publication := record.Publication()
+	return PublicationInput{
+		Title:           record.Title,
+		Type:            record.Type,
+		Abstract:        record.Abstract,
+		DOI:             record.DOI,
+		PublicationDate: publication.PublicationDate.Format("2006-01-02"),
+		Journal:         record.Container,
+		Volume:          record.Volume,
+		Issue:           record.Issue,
+		Pages:           record.Pages,
+		Publisher:       record.Publisher,
+		URL:             record.URL,
+		Keywords:        record.Keywords,
+		AuthorNames:     record.Authors,
+	}
+}
@@ -2,19 +2,24 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"freescholar-backend/config"
+	"freescholar-backend/internal/bibliography"
+	"freescholar-backend/internal/indexer"
 	"freescholar-backend/internal/models"
+	"freescholar-backend/internal/search"
 	"freescholar-backend/pkg/elasticsearch"
 
 	"github.com/gin-gonic/gin"
-	"github.com/olivere/elastic/v7"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // PublicationHandler handles HTTP requests related to publications
@@ -36,6 +41,7 @@ func NewPublicationHandler(db *gorm.DB, esClient *elasticsearch.Client, cfg *con
 // PublicationInput represents input for creating/updating publications
 type PublicationInput struct {
 	Title           string    `json:"title" binding:"required"`
+	Type            string    `json:"type" binding:"omitempty,oneof=article conference book other"`
 	Abstract        string    `json:"abstract"`
 	DOI             string    `json:"doi"`
 	PublicationDate string    `json:"publication_date"` // Format: YYYY-MM-DD
@@ -47,6 +53,7 @@ type PublicationInput struct {
 	URL             string    `json:"url"`
 	Keywords        []string  `json:"keywords"`
 	Authors         []uint    `json:"authors"` // Author IDs
+	AuthorNames     []string  `json:"author_names"` // Matched by name or created; they follow Authors in the byline
 }
 
 // GetPublications handles fetching multiple publications with filtering and pagination
@@ -66,46 +73,81 @@ func (h *PublicationHandler) GetPublications(c *gin.Context) {
 	
 	offset := (page - 1) * limit
 
-	// If search query is provided, use Elasticsearch
+	// Reference managers can ask for the page in RIS, BibTeX or CSL-JSON
+	c.Header("Vary", "Accept")
+	format, export, err := requestedFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filters, err := parseSearchFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Parse the query language; syntax errors point at the offending position
+	var parsed *search.Query
 	if query != "" {
-		// Create search query for Elasticsearch
-		esQuery := elastic.NewMultiMatchQuery(query, 
-			"title^3", // Boost title relevance
-			"abstract^2",
-			"authors",
-			"keywords",
-			"journal",
-		).Type("best_fields").Fuzziness("AUTO")
-		
-		searchResult, err := h.esClient.Search().
-			Index("publications").
-			Query(esQuery).
-			From(offset).
-			Size(limit).
-			Sort("_score", false).  // Sort by relevance
-			Sort("publication_date", false). // Then by date (newest first)
-			Do(context.Background())
-			
+		parsed, err = search.ParseQuery(query)
+		if err != nil {
+			var syntaxErr *search.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": syntaxErr.Error(), "position": syntaxErr.Pos})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Full-text queries and filters go to Elasticsearch
+	if query != "" || !filters.Empty() || c.Query("facets") == "true" {
+		// Fragment size can be tuned per request within sane bounds
+		fragmentSize := h.config.ES.HighlightFragmentSize
+		if value := c.Query("fragment_size"); value != "" {
+			size, err := strconv.Atoi(value)
+			if err != nil || size < 20 || size > 1000 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "fragment_size must be between 20 and 1000"})
+				return
+			}
+			fragmentSize = size
+		}
+
+		searchRequest := search.PublicationRequest{
+			Query:        parsed,
+			Filters:      filters,
+			From:         offset,
+			Size:         limit,
+			Facets:       c.DefaultQuery("facets", "true") == "true",
+			FragmentSize: fragmentSize,
+			Fragments:    h.config.ES.HighlightFragments,
+			SuggestBelow: h.config.ES.SuggestBelow,
+		}
+		searchResult, err := search.NewPublicationSearch(h.esClient, searchRequest).Do(context.Background())
+
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Search error"})
 			return
 		}
 
-		// Process search results
-		var publications []models.PublicationSearch
-		var total = searchResult.TotalHits()
-		
-		for _, hit := range searchResult.Hits.Hits {
-			var publication models.PublicationSearch
-			err := json.Unmarshal(hit.Source, &publication)
-			if err != nil {
-				continue
+		result := search.ParsePublicationResult(searchResult, searchRequest)
+		total := result.Total
+
+		if export {
+			ids := make([]uint, len(result.Publications))
+			for i, hit := range result.Publications {
+				ids[i] = hit.ID
 			}
-			publications = append(publications, publication)
+			h.writePage(c, format, ids)
+			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"publications": publications,
+			"publications": result.Publications,
+			"facets":       result.Facets,
+			"did_you_mean": result.DidYouMean,
 			"total":        total,
 			"page":         page,
 			"limit":        limit,
@@ -114,30 +156,17 @@ func (h *PublicationHandler) GetPublications(c *gin.Context) {
 		return
 	}
 
-	// Otherwise, use database query
+	// Otherwise, list from the database
 	var publications []models.Publication
 	var total int64
 
 	db := h.db.Model(&models.Publication{})
-	
-	// Filter by journal if provided
-	if journal := c.Query("journal"); journal != "" {
-		db = db.Where("journal LIKE ?", "%"+journal+"%")
-	}
-	
-	// Filter by date range if provided
-	if fromDate := c.Query("from_date"); fromDate != "" {
-		db = db.Where("publication_date >= ?", fromDate)
-	}
-	if toDate := c.Query("to_date"); toDate != "" {
-		db = db.Where("publication_date <= ?", toDate)
-	}
-	
+
 	// Get total count
 	db.Count(&total)
-	
+
 	// Get paginated results with preloaded relationships
-	err := db.Preload("Authors").Preload("Keywords").
+	err = db.Preload("Authors").Preload("Keywords").
 		Offset(offset).
 		Limit(limit).
 		Order("publication_date DESC").
@@ -148,6 +177,15 @@ func (h *PublicationHandler) GetPublications(c *gin.Context) {
 		return
 	}
 
+	if export {
+		ids := make([]uint, len(publications))
+		for i, publication := range publications {
+			ids[i] = publication.ID
+		}
+		h.writePage(c, format, ids)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"publications": publications,
 		"total":        total,
@@ -157,25 +195,226 @@ func (h *PublicationHandler) GetPublications(c *gin.Context) {
 	})
 }
 
+// SuggestPublications handles autocomplete of titles, author names, journals and keywords
+func (h *PublicationHandler) SuggestPublications(c *gin.Context) {
+	prefix := strings.TrimSpace(c.Query("q"))
+	if prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if limit < 1 || limit > 20 {
+		limit = 5
+	}
+
+	// type restricts suggestions to some kinds, e.g. type=titles,authors
+	kinds := search.SuggestKinds
+	if value := c.Query("type"); value != "" {
+		kinds = nil
+		for _, kind := range strings.Split(value, ",") {
+			if !slices.Contains(search.SuggestKinds, kind) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "type must be a list of " + strings.Join(search.SuggestKinds, ", ")})
+				return
+			}
+			kinds = append(kinds, kind)
+		}
+	}
+
+	suggestions, err := search.Suggest(c.Request.Context(), h.esClient, prefix, kinds, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Suggestion error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+// parseSearchFilters reads the search filters from the query string.
+// journal, author and keyword select facet values exactly and may be repeated
+// to match any of them; journal_contains matches part of the journal name.
+func parseSearchFilters(c *gin.Context) (search.Filters, error) {
+	filters := search.Filters{
+		Journals:    c.QueryArray("journal"),
+		Authors:     c.QueryArray("author"),
+		Keywords:    c.QueryArray("keyword"),
+		JournalText: strings.TrimSpace(c.Query("journal_contains")),
+		FromDate:    c.Query("from_date"),
+		ToDate:      c.Query("to_date"),
+	}
+
+	for _, date := range []string{filters.FromDate, filters.ToDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return filters, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+		}
+	}
+
+	for name, target := range map[string]*int{"year_from": &filters.YearFrom, "year_to": &filters.YearTo} {
+		if value := c.Query(name); value != "" {
+			year, err := strconv.Atoi(value)
+			if err != nil || year < 1 || year > 9999 {
+				return filters, fmt.Errorf("invalid %s %q", name, value)
+			}
+			*target = year
+		}
+	}
+
+	for name, target := range map[string]**int{"min_citations": &filters.MinCitations, "max_citations": &filters.MaxCitations} {
+		if value := c.Query(name); value != "" {
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 0 {
+				return filters, fmt.Errorf("invalid %s %q", name, value)
+			}
+			*target = &count
+		}
+	}
+
+	if value := c.Query("has_pdf"); value != "" {
+		hasPDF, err := strconv.ParseBool(value)
+		if err != nil {
+			return filters, fmt.Errorf("invalid has_pdf %q", value)
+		}
+		filters.HasPDF = &hasPDF
+	}
+
+	return filters, nil
+}
+
 // GetPublication handles fetching a single publication by ID
 func (h *PublicationHandler) GetPublication(c *gin.Context) {
 	id := c.Param("id")
+
+	c.Header("Vary", "Accept")
+	format, export, err := requestedFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if export {
+		publicationID, _ := strconv.ParseUint(id, 10, 64)
+		records, err := bibliography.Load(h.db, []uint{uint(publicationID)})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch publication"})
+			return
+		}
+		if len(records) == 0 {
+			h.publicationNotFound(c, uint(publicationID))
+			return
+		}
+		writeRecords(c, format, records, "")
+		return
+	}
 	
 	var publication models.Publication
-	err := h.db.Preload("Authors").Preload("Keywords").First(&publication, id).Error
+	err = h.db.Preload("Authors").Preload("Keywords").First(&publication, id).Error
 	
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found"})
+		publicationID, _ := strconv.ParseUint(id, 10, 64)
+		h.publicationNotFound(c, uint(publicationID))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"publication": publication})
 }
 
+// publicationNotFound redirects IDs of merged publications to the publication
+// that absorbed them and responds 404 otherwise
+func (h *PublicationHandler) publicationNotFound(c *gin.Context, id uint) {
+	if target, ok := models.ResolveRedirect(h.db, id); ok {
+		location := "/api/publication/" + strconv.Itoa(int(target))
+		if c.Request.URL.RawQuery != "" {
+			location += "?" + c.Request.URL.RawQuery
+		}
+		c.Redirect(http.StatusMovedPermanently, location)
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found"})
+}
+
+// addAuthorsByName links authors given by name to a publication, matching
+// existing authors or creating them, from byline position first on
+func addAuthorsByName(tx *gorm.DB, publicationID uint, names []string, first int) error {
+	ids, err := authorIDsByName(tx, names)
+	if err != nil {
+		return err
+	}
+
+	for i, authorID := range ids {
+		pubAuthor := models.PublicationAuthor{
+			PublicationID: publicationID,
+			AuthorID:      authorID,
+			Order:         first + i,
+		}
+		if err := tx.Create(&pubAuthor).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// authorIDsByName matches or creates the authors of the given names, skipping blank ones
+func authorIDsByName(tx *gorm.DB, names []string) ([]uint, error) {
+	ids := make([]uint, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		author, created, err := bibliography.FindOrCreateAuthor(tx, name)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			if err := indexer.IndexAuthor(tx, author.ID); err != nil {
+				return nil, err
+			}
+		}
+		ids = append(ids, author.ID)
+	}
+	return ids, nil
+}
+
+// writePage responds with a page of publications in a reference format,
+// in the order of ids
+func (h *PublicationHandler) writePage(c *gin.Context, format bibliography.Format, ids []uint) {
+	records, err := bibliography.Load(h.db, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch publications"})
+		return
+	}
+	writeRecords(c, format, records, "")
+}
+
+// GetRelatedPublications handles recommending publications related to one publication
+func (h *PublicationHandler) GetRelatedPublications(c *gin.Context) {
+	var publication models.Publication
+	if err := h.db.First(&publication, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	related, err := search.Related(c.Request.Context(), h.db, h.esClient, publication.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find related publications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"related": related})
+}
+
 // CreatePublication handles creating a new publication
 func (h *PublicationHandler) CreatePublication(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	_, exists := c.Get("userID")
+	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -187,6 +426,21 @@ func (h *PublicationHandler) CreatePublication(c *gin.Context) {
 		return
 	}
 
+	// DOIs are stored normalized, so variants of a known DOI are duplicates
+	doi := models.NormalizeDOI(input.DOI)
+	if doi != "" {
+		var existing models.Publication
+		if h.db.Unscoped().Where("doi = ?", doi).Limit(1).Find(&existing).RowsAffected > 0 {
+			// Trashed publications keep their DOI until they are purged
+			if existing.DeletedAt.Valid {
+				c.JSON(http.StatusConflict, gin.H{"error": "A deleted publication with this DOI is in the trash; restore it instead", "publication_id": existing.ID})
+				return
+			}
+			c.JSON(http.StatusConflict, gin.H{"error": "A publication with this DOI already exists", "publication_id": existing.ID})
+			return
+		}
+	}
+
 	// Parse publication date
 	pubDate, err := time.Parse("2006-01-02", input.PublicationDate)
 	if err != nil {
@@ -204,8 +458,9 @@ func (h *PublicationHandler) CreatePublication(c *gin.Context) {
 	// Create publication
 	publication := models.Publication{
 		Title:           input.Title,
+		Type:            input.Type,
 		Abstract:        input.Abstract,
-		DOI:             input.DOI,
+		DOI:             models.DOI(doi),
 		PublicationDate: pubDate,
 		Journal:         input.Journal,
 		Volume:          input.Volume,
@@ -213,6 +468,7 @@ func (h *PublicationHandler) CreatePublication(c *gin.Context) {
 		Pages:           input.Pages,
 		Publisher:       input.Publisher,
 		URL:             input.URL,
+		OwnerID:         userID.(uint),
 	}
 
 	if err := tx.Create(&publication).Error; err != nil {
@@ -267,27 +523,65 @@ func (h *PublicationHandler) CreatePublication(c *gin.Context) {
 		}
 	}
 
+	if err := addAuthorsByName(tx, publication.ID, input.AuthorNames, len(input.Authors)); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to associate author"})
+		return
+	}
+
+	// Link references to this publication that were waiting for its DOI
+	if err := linkCitations(tx, publication); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link citations"})
+		return
+	}
+
+	// Schedule indexing in Elasticsearch once authors and keywords are in place
+	if err := indexer.IndexPublications(tx, publication.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule indexing"})
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
 		return
 	}
 
-	// Index in Elasticsearch
-	go h.indexPublication(publication)
-
 	c.JSON(http.StatusCreated, gin.H{
 		"message":     "Publication created successfully",
 		"publication": publication,
 	})
 }
 
-// Completing the UpdatePublication method that was cut off
+// PublicationUpdateInput represents input for updating publications. Only
+// the fields present in the request are changed; an empty list clears
+// keywords or authors.
+type PublicationUpdateInput struct {
+	Title           *string   `json:"title" binding:"omitempty,min=1"`
+	Type            *string   `json:"type" binding:"omitempty,oneof=article conference book other"`
+	Abstract        *string   `json:"abstract"`
+	DOI             *string   `json:"doi"`
+	PublicationDate *string   `json:"publication_date"` // Format: YYYY-MM-DD
+	Journal         *string   `json:"journal"`
+	Volume          *string   `json:"volume"`
+	Issue           *string   `json:"issue"`
+	Pages           *string   `json:"pages"`
+	Publisher       *string   `json:"publisher"`
+	URL             *string   `json:"url"`
+	Keywords        *[]string `json:"keywords"`
+	Authors         *[]uint   `json:"authors"`      // Author IDs
+	AuthorNames     *[]string `json:"author_names"` // Matched by name or created; they follow Authors in the byline
+}
+
+// UpdatePublication handles partially updating a publication and records
+// the change as a revision
 func (h *PublicationHandler) UpdatePublication(c *gin.Context) {
 	id := c.Param("id")
-	
+
 	// Get user ID from context (set by auth middleware)
-	_, exists := c.Get("userID")
+	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -300,18 +594,15 @@ func (h *PublicationHandler) UpdatePublication(c *gin.Context) {
 		return
 	}
 
-	var input PublicationInput
+	var input PublicationUpdateInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Parse publication date
-	var pubDate time.Time
-	var err error
-	if input.PublicationDate != "" {
-		pubDate, err = time.Parse("2006-01-02", input.PublicationDate)
-		if err != nil {
+	// Validate the publication date
+	if input.PublicationDate != nil {
+		if _, err := time.Parse("2006-01-02", *input.PublicationDate); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid publication date format. Use YYYY-MM-DD"})
 			return
 		}
@@ -324,96 +615,76 @@ func (h *PublicationHandler) UpdatePublication(c *gin.Context) {
 		return
 	}
 
-	// Update publication fields
-	updates := map[string]interface{}{
-		"title":           input.Title,
-		"abstract":        input.Abstract,
-		"doi":             input.DOI,
-		"journal":         input.Journal,
-		"volume":          input.Volume,
-		"issue":           input.Issue,
-		"pages":           input.Pages,
-		"publisher":       input.Publisher,
-		"url":             input.URL,
-	}
-	
-	// Only update publication date if provided
-	if !pubDate.IsZero() {
-		updates["publication_date"] = pubDate
+	if err := models.LockPublication(tx, publication.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load publication"})
+		return
 	}
 
-	if err := tx.Model(&publication).Updates(updates).Error; err != nil {
+	before, err := models.TakeSnapshot(tx, publication.ID)
+	if err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update publication"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load publication"})
 		return
 	}
 
-	// Update keywords (clear and re-add)
-	if len(input.Keywords) > 0 {
-		// Remove existing keywords association
-		if err := tx.Model(&publication).Association("Keywords").Clear(); err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear keywords"})
-			return
+	// Apply the provided fields to a copy of the current state
+	after := before
+	for field, value := range map[*string]*string{
+		&after.Title:     input.Title,
+		&after.Type:      input.Type,
+		&after.Abstract:  input.Abstract,
+		&after.Journal:   input.Journal,
+		&after.Volume:    input.Volume,
+		&after.Issue:     input.Issue,
+		&after.Pages:     input.Pages,
+		&after.Publisher: input.Publisher,
+		&after.URL:       input.URL,
+	} {
+		if value != nil {
+			*field = *value
 		}
+	}
+	if input.DOI != nil {
+		after.DOI = models.NormalizeDOI(*input.DOI)
+	}
+	if input.PublicationDate != nil {
+		after.PublicationDate = *input.PublicationDate
+	}
+	if input.Keywords != nil {
+		after.Keywords = normalizeKeywords(*input.Keywords)
+	}
 
-		// Add new keywords
-		for _, keyword := range input.Keywords {
-			var existingKeyword models.Keyword
-			result := tx.Where("name = ?", keyword).First(&existingKeyword)
-			
-			if result.RowsAffected == 0 {
-				// Create new keyword if it doesn't exist
-				existingKeyword = models.Keyword{Name: keyword}
-				if err := tx.Create(&existingKeyword).Error; err != nil {
+	// Replace authors if either list is provided
+	if input.Authors != nil || input.AuthorNames != nil {
+		after.Authors = []uint{}
+		if input.Authors != nil {
+			for _, authorID := range *input.Authors {
+				var author models.Author
+				if err := tx.First(&author, authorID).Error; err != nil {
 					tx.Rollback()
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create keyword"})
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Author not found: " + strconv.Itoa(int(authorID))})
 					return
 				}
-			}
-			
-			// Associate keyword with publication
-			if err := tx.Model(&publication).Association("Keywords").Append(&existingKeyword); err != nil {
-				tx.Rollback()
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to associate keyword"})
-				return
+				after.Authors = append(after.Authors, author.ID)
 			}
 		}
-	}
-
-	// Update authors (if provided)
-	if len(input.Authors) > 0 {
-		// Delete existing publication-author relationships
-		if err := tx.Where("publication_id = ?", publication.ID).Delete(&models.PublicationAuthor{}).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear author associations"})
-			return
-		}
-
-		// Create new publication-author relationships
-		for i, authorID := range input.Authors {
-			var author models.Author
-			if err := tx.First(&author, authorID).Error; err != nil {
-				tx.Rollback()
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Author not found: " + strconv.Itoa(int(authorID))})
-				return
-			}
-			
-			// Create publication-author relationship with order
-			pubAuthor := models.PublicationAuthor{
-				PublicationID: publication.ID,
-				AuthorID:      author.ID,
-				Order:         i,
-			}
-			
-			if err := tx.Create(&pubAuthor).Error; err != nil {
+		if input.AuthorNames != nil {
+			ids, err := authorIDsByName(tx, *input.AuthorNames)
+			if err != nil {
 				tx.Rollback()
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to associate author"})
 				return
 			}
+			after.Authors = append(after.Authors, ids...)
 		}
 	}
 
+	revision, ok := h.applyRevision(c, tx, publication.ID, userID.(uint), models.RevisionUpdate, before, after, nil)
+	if !ok {
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
@@ -423,16 +694,80 @@ func (h *PublicationHandler) UpdatePublication(c *gin.Context) {
 	// Re-fetch the publication with updated relationships
 	h.db.Preload("Authors").Preload("Keywords").First(&publication, publication.ID)
 
-	// Update in Elasticsearch
-	go h.indexPublication(publication)
-
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"message":     "Publication updated successfully",
 		"publication": publication,
-	})
+	}
+	if revision != nil {
+		response["revision"] = revision.Number
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// applyRevision changes a publication from before to after inside tx,
+// records the revision and schedules reindexing. It returns nil if nothing
+// changed. On failure it rolls back, responds and returns false.
+func (h *PublicationHandler) applyRevision(c *gin.Context, tx *gorm.DB, publicationID, userID uint, action string, before, after models.PublicationSnapshot, restoredFrom *int) (*models.PublicationRevision, bool) {
+	// Another publication may hold the new DOI
+	if after.DOI != "" && after.DOI != before.DOI {
+		var existing models.Publication
+		if tx.Unscoped().Where("doi = ? AND id <> ?", after.DOI, publicationID).Limit(1).Find(&existing).RowsAffected > 0 {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, gin.H{"error": "A publication with this DOI already exists", "publication_id": existing.ID})
+			return nil, false
+		}
+	}
+
+	if err := models.ApplySnapshot(tx, publicationID, before, after); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update publication"})
+		return nil, false
+	}
+
+	revision, err := models.RecordRevision(tx, publicationID, userID, action, before, after, restoredFrom)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return nil, false
+	}
+	if revision == nil {
+		return nil, true
+	}
+
+	// A new DOI may resolve pending references
+	if after.DOI != before.DOI {
+		publication := models.Publication{Model: gorm.Model{ID: publicationID}, DOI: models.DOI(after.DOI)}
+		if err := linkCitations(tx, publication); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link citations"})
+			return nil, false
+		}
+	}
+
+	// Schedule the Elasticsearch update
+	if err := indexer.IndexPublications(tx, publicationID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule indexing"})
+		return nil, false
+	}
+
+	return revision, true
 }
 
-// DeletePublication handles deleting a publication
+// normalizeKeywords trims keywords and returns them sorted without duplicates,
+// the order revisions store them in
+func normalizeKeywords(keywords []string) []string {
+	out := make([]string, 0, len(keywords))
+	for _, keyword := range keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			out = append(out, keyword)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// DeletePublication handles moving a publication to the trash
 func (h *PublicationHandler) DeletePublication(c *gin.Context) {
 	id := c.Param("id")
 	
@@ -457,24 +792,32 @@ func (h *PublicationHandler) DeletePublication(c *gin.Context) {
 		return
 	}
 
-	// Delete all publication-author relationships
-	if err := tx.Where("publication_id = ?", publication.ID).Delete(&models.PublicationAuthor{}).Error; err != nil {
+	// Move the publication to the trash; authors, keywords and references stay
+	// linked so it can be restored until the purge removes it for good
+	if err := tx.Delete(&publication).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete author associations"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete publication"})
 		return
 	}
 
-	// Remove all keyword associations (uses many-to-many relationship)
-	if err := tx.Model(&publication).Association("Keywords").Clear(); err != nil {
+	// Schedule removal from Elasticsearch
+	if err := indexer.DeletePublication(tx, publication.ID); err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear keyword associations"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule indexing"})
 		return
 	}
 
-	// Delete the publication
-	if err := tx.Delete(&publication).Error; err != nil {
+	// Publications it cited lose a citation
+	cited, err := models.CitedIDs(tx, publication.ID)
+	if err == nil {
+		err = models.RecountCitations(tx, cited...)
+	}
+	if err == nil {
+		err = indexer.IndexPublications(tx, cited...)
+	}
+	if err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete publication"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update citation counts"})
 		return
 	}
 
@@ -484,63 +827,355 @@ func (h *PublicationHandler) DeletePublication(c *gin.Context) {
 		return
 	}
 
-	// Delete from Elasticsearch
-	go func() {
-		ctx := context.Background()
-		_, err := h.esClient.Delete().
-			Index("publications").
-			Id(id).
-			Do(ctx)
-		
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Publication moved to the trash",
+	})
+}
+
+// PublicationMergeInput represents input for merging duplicate publications into one
+type PublicationMergeInput struct {
+	DuplicateIDs []uint `json:"duplicate_ids" binding:"required,min=1"`
+}
+
+// MergePublications moves the authors, keywords, citations and files of the
+// duplicate publications onto the surviving publication identified by the
+// URL, deletes the duplicates and redirects their IDs to the survivor
+func (h *PublicationHandler) MergePublications(c *gin.Context) {
+	id := c.Param("id")
+
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input PublicationMergeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check if the surviving publication exists
+	var survivor models.Publication
+	if err := h.db.First(&survivor, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found"})
+		return
+	}
+
+	// Load duplicates, rejecting self-merges and unknown IDs
+	var duplicates []models.Publication
+	for _, duplicateID := range input.DuplicateIDs {
+		if duplicateID == survivor.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge a publication into itself"})
+			return
+		}
+
+		var duplicate models.Publication
+		if err := h.db.First(&duplicate, duplicateID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Publication not found: " + strconv.Itoa(int(duplicateID))})
+			return
+		}
+		if survivor.DOI != "" && duplicate.DOI != "" && models.NormalizeDOI(string(survivor.DOI)) != models.NormalizeDOI(string(duplicate.DOI)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Publication " + strconv.Itoa(int(duplicateID)) + " has a different DOI"})
+			return
+		}
+		duplicates = append(duplicates, duplicate)
+	}
+
+	// Start a transaction
+	tx := h.db.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	if err := models.LockPublication(tx, survivor.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load publication"})
+		return
+	}
+
+	before, err := models.TakeSnapshot(tx, survivor.ID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load publication"})
+		return
+	}
+
+	var affected []uint
+	for _, duplicate := range duplicates {
+		cited, err := mergePublicationInto(tx, duplicate.ID, survivor.ID)
 		if err != nil {
-			// Log the error but don't fail the response
-			// since MySQL deletion was successful
-			log.Printf("Error deleting publication from Elasticsearch: %v", err)
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move associations"})
+			return
 		}
-	}()
+		affected = append(affected, cited...)
+
+		// Keep details the surviving record is missing. This must come first:
+		// clearing the DOI below also clears it on duplicate.
+		fillPublicationDetails(&survivor, duplicate)
+
+		// Free the DOI for the survivor; soft-deleted rows still hold the unique index
+		if err := tx.Model(&duplicate).UpdateColumn("doi", nil).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete duplicate publication"})
+			return
+		}
+
+		if err := tx.Delete(&duplicate).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete duplicate publication"})
+			return
+		}
+
+		if err := models.AddRedirect(tx, duplicate.ID, survivor.ID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redirect duplicate publication"})
+			return
+		}
+
+		if err := indexer.DeletePublication(tx, duplicate.ID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule indexing"})
+			return
+		}
+	}
+
+	if err := tx.Omit(clause.Associations).Save(&survivor).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update publication"})
+		return
+	}
+
+	// Record what the survivor gained as a revision
+	after, err := models.TakeSnapshot(tx, survivor.ID)
+	if err == nil {
+		_, err = models.RecordRevision(tx, survivor.ID, userID.(uint), models.RevisionMerge, before, after, nil)
+	}
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	// The survivor inherits the duplicates' citations; publications they cited may lose one
+	affected = append(affected, survivor.ID)
+	if err := models.RecountCitations(tx, affected...); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update citation counts"})
+		return
+	}
+
+	if err := indexer.IndexPublications(tx, affected...); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule indexing"})
+		return
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	h.db.Preload("Authors").Preload("Keywords").First(&survivor, survivor.ID)
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Publication deleted successfully",
+		"message":     "Publications merged successfully",
+		"publication": survivor,
+		"merged":      len(duplicates),
 	})
 }
 
-// indexPublication indexes a publication in Elasticsearch
-func (h *PublicationHandler) indexPublication(publication models.Publication) {
-	// Create a search model of the publication
-	var authors []string
-	for _, author := range publication.Authors {
-		authors = append(authors, author.Name)
+// mergePublicationInto moves the authors, keywords, citations and files of
+// the duplicate publication onto the survivor and returns the IDs of the
+// publications the duplicate cited, whose citation counts may change.
+// Authors the survivor lacks are appended to its byline in their order;
+// references and citations it already has are dropped.
+func mergePublicationInto(tx *gorm.DB, duplicateID, survivorID uint) ([]uint, error) {
+	// Authors
+	var lastOrder struct{ Max *int }
+	if err := tx.Model(&models.PublicationAuthor{}).
+		Select("MAX(`order`) AS max").
+		Where("publication_id = ?", survivorID).
+		Scan(&lastOrder).Error; err != nil {
+		return nil, err
+	}
+	order := 0
+	if lastOrder.Max != nil {
+		order = *lastOrder.Max + 1
 	}
 
-	var keywords []string
-	for _, keyword := range publication.Keywords {
-		keywords = append(keywords, keyword.Name)
+	var rows []models.PublicationAuthor
+	if err := tx.Where("publication_id = ?", duplicateID).Order("`order` ASC").Find(&rows).Error; err != nil {
+		return nil, err
 	}
+	for _, row := range rows {
+		var existing int64
+		if err := tx.Model(&models.PublicationAuthor{}).
+			Where("publication_id = ? AND author_id = ?", survivorID, row.AuthorID).
+			Count(&existing).Error; err != nil {
+			return nil, err
+		}
 
-	pubSearch := models.PublicationSearch{
-		ID:              publication.ID,
-		Title:           publication.Title,
-		Abstract:        publication.Abstract,
-		Authors:         authors,
-		Keywords:        keywords,
-		DOI:             publication.DOI,
-		PublicationDate: publication.PublicationDate,
-		Journal:         publication.Journal,
-		CitationCount:   publication.CitationCount,
+		if existing > 0 {
+			// The join table doubles as the many2many table, so remove the row for good
+			if err := tx.Unscoped().Delete(&row).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := tx.Model(&row).Updates(map[string]interface{}{"publication_id": survivorID, "order": order}).Error; err != nil {
+			return nil, err
+		}
+		order++
 	}
 
-	// Index document in Elasticsearch
-	ctx := context.Background()
-	id := strconv.Itoa(int(publication.ID))
-	
-	_, err := h.esClient.Index().
-		Index("publications").
-		Id(id).
-		BodyJson(pubSearch).
-		Do(ctx)
-		
+	// Keywords; the join table ignores pairs the survivor already has
+	duplicate := models.Publication{Model: gorm.Model{ID: duplicateID}}
+	survivor := models.Publication{Model: gorm.Model{ID: survivorID}}
+	var keywords []models.Keyword
+	if err := tx.Model(&duplicate).Association("Keywords").Find(&keywords); err != nil {
+		return nil, err
+	}
+	if len(keywords) > 0 {
+		if err := tx.Model(&survivor).Association("Keywords").Append(&keywords); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Model(&duplicate).Association("Keywords").Clear(); err != nil {
+		return nil, err
+	}
+
+	// References of the duplicate, appended to the survivor's list
+	cited, err := models.CitedIDs(tx, duplicateID)
 	if err != nil {
-		// Log error but don't stop execution
-		log.Printf("Failed to index publication in Elasticsearch: %v", err)
+		return nil, err
+	}
+
+	var lastPosition struct{ Max *int }
+	if err := tx.Model(&models.Citation{}).
+		Select("MAX(position) AS max").
+		Where("citing_id = ?", survivorID).
+		Scan(&lastPosition).Error; err != nil {
+		return nil, err
+	}
+	position := 0
+	if lastPosition.Max != nil {
+		position = *lastPosition.Max + 1
+	}
+
+	var references []models.Citation
+	if err := tx.Where("citing_id = ?", duplicateID).Order("position ASC").Find(&references).Error; err != nil {
+		return nil, err
+	}
+	for _, reference := range references {
+		query := tx.Model(&models.Citation{}).Where("citing_id = ?", survivorID)
+		switch {
+		case reference.CitedID != nil:
+			query = query.Where("cited_id = ?", *reference.CitedID)
+		case reference.DOI != "":
+			query = query.Where("doi = ?", reference.DOI)
+		default:
+			query = query.Where("raw_reference = ?", reference.RawReference)
+		}
+		var existing int64
+		if err := query.Count(&existing).Error; err != nil {
+			return nil, err
+		}
+
+		if existing > 0 || (reference.CitedID != nil && *reference.CitedID == survivorID) {
+			if err := tx.Delete(&reference).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := tx.Model(&reference).Updates(map[string]interface{}{"citing_id": survivorID, "position": position}).Error; err != nil {
+			return nil, err
+		}
+		position++
 	}
-}
\ No newline at end of file
+
+	// Citations of the duplicate now cite the survivor
+	var citations []models.Citation
+	if err := tx.Where("cited_id = ?", duplicateID).Find(&citations).Error; err != nil {
+		return nil, err
+	}
+	for _, citation := range citations {
+		var existing int64
+		if err := tx.Model(&models.Citation{}).
+			Where("citing_id = ? AND cited_id = ?", citation.CitingID, survivorID).
+			Count(&existing).Error; err != nil {
+			return nil, err
+		}
+
+		if existing > 0 || citation.CitingID == survivorID {
+			if err := tx.Delete(&citation).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := tx.Model(&citation).Update("cited_id", survivorID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// Files; the survivor keeps its own copy of a PDF both have
+	var files []models.File
+	if err := tx.Omit("text").Where("publication_id = ?", duplicateID).Find(&files).Error; err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		var existing int64
+		if err := tx.Model(&models.File{}).
+			Where("publication_id = ? AND checksum = ?", survivorID, file.Checksum).
+			Count(&existing).Error; err != nil {
+			return nil, err
+		}
+
+		if existing > 0 {
+			if err := tx.Delete(&file).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := tx.Model(&file).Update("publication_id", survivorID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return cited, nil
+}
+
+// fillPublicationDetails copies non-empty fields of the duplicate onto blank fields of the survivor
+func fillPublicationDetails(survivor *models.Publication, duplicate models.Publication) {
+	if survivor.Abstract == "" {
+		survivor.Abstract = duplicate.Abstract
+	}
+	if survivor.DOI == "" {
+		survivor.DOI = duplicate.DOI
+	}
+	if survivor.Journal == "" {
+		survivor.Journal = duplicate.Journal
+	}
+	if survivor.Volume == "" {
+		survivor.Volume = duplicate.Volume
+	}
+	if survivor.Issue == "" {
+		survivor.Issue = duplicate.Issue
+	}
+	if survivor.Pages == "" {
+		survivor.Pages = duplicate.Pages
+	}
+	if survivor.Publisher == "" {
+		survivor.Publisher = duplicate.Publisher
+	}
+	if survivor.URL == "" {
+		survivor.URL = duplicate.URL
+	}
+	if survivor.PDFPath == "" {
+		survivor.PDFPath = duplicate.PDFPath
+	}
+}
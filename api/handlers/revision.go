@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"freescholar-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRevisions handles listing the revisions of a publication, newest first.
+// Snapshots are left out; fetch a single revision for its full state.
+func (h *PublicationHandler) GetRevisions(c *gin.Context) {
+	var publication models.Publication
+	if err := h.db.First(&publication, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found"})
+		return
+	}
+
+	page, limit := citationPagination(c)
+
+	query := h.db.Model(&models.PublicationRevision{}).Where("publication_id = ?", publication.ID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count revisions"})
+		return
+	}
+
+	revisions := []models.PublicationRevision{}
+	if err := query.Omit("snapshot").Order("number DESC").Offset((page - 1) * limit).Limit(limit).Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": revisions,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+		"pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// GetRevision handles fetching one revision of a publication with its changes and snapshot
+func (h *PublicationHandler) GetRevision(c *gin.Context) {
+	revision, ok := h.findRevision(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// RestoreRevision handles returning a publication to the state of an
+// earlier revision. The restore is itself recorded as a new revision.
+func (h *PublicationHandler) RestoreRevision(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	revision, ok := h.findRevision(c)
+	if !ok {
+		return
+	}
+
+	// Authors deleted since the revision cannot be linked again
+	after := *revision.Snapshot
+	authorIDs := slices.Compact(slices.Sorted(slices.Values(after.Authors)))
+	var found int64
+	if len(authorIDs) > 0 {
+		if err := h.db.Model(&models.Author{}).Where("id IN ?", authorIDs).Count(&found).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch authors"})
+			return
+		}
+	}
+	if int(found) < len(authorIDs) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Some authors of this revision no longer exist"})
+		return
+	}
+
+	// Start a transaction
+	tx := h.db.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	if err := models.LockPublication(tx, revision.PublicationID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load publication"})
+		return
+	}
+
+	before, err := models.TakeSnapshot(tx, revision.PublicationID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load publication"})
+		return
+	}
+
+	restored, ok := h.applyRevision(c, tx, revision.PublicationID, userID.(uint), models.RevisionRestore, before, after, &revision.Number)
+	if !ok {
+		return
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	var publication models.Publication
+	h.db.Preload("Authors").Preload("Keywords").First(&publication, revision.PublicationID)
+
+	response := gin.H{
+		"message":     "Revision restored successfully",
+		"publication": publication,
+	}
+	if restored != nil {
+		response["revision"] = restored.Number
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// findRevision loads the revision named by the URL, responding with an error if it is missing
+func (h *PublicationHandler) findRevision(c *gin.Context) (models.PublicationRevision, bool) {
+	var revision models.PublicationRevision
+
+	var publication models.Publication
+	if err := h.db.First(&publication, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found"})
+		return revision, false
+	}
+
+	number, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return revision, false
+	}
+
+	if err := h.db.Where("publication_id = ? AND number = ?", publication.ID, number).First(&revision).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return revision, false
+	}
+	return revision, true
+}
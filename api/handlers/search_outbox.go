@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"freescholar-backend/config"
+	"freescholar-backend/internal/indexer"
+	"freescholar-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SearchOutboxHandler handles admin requests about pending and dead-lettered search index changes
+type SearchOutboxHandler struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+// NewSearchOutboxHandler creates a new search outbox handler
+func NewSearchOutboxHandler(db *gorm.DB, cfg *config.Config) *SearchOutboxHandler {
+	return &SearchOutboxHandler{
+		db:     db,
+		config: cfg,
+	}
+}
+
+// GetEvents lists outbox events. status=dead (default) shows dead letters, status=pending shows the backlog.
+func (h *SearchOutboxHandler) GetEvents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	// Ensure reasonable pagination values
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	db := h.db.Model(&models.SearchOutbox{})
+
+	switch c.DefaultQuery("status", "dead") {
+	case "dead":
+		db = db.Where("dead_at IS NOT NULL")
+	case "pending":
+		db = db.Where("dead_at IS NULL AND processed_at IS NULL")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be dead or pending"})
+		return
+	}
+
+	if entity := c.Query("entity"); entity != "" {
+		db = db.Where("entity = ?", entity)
+	}
+
+	var total int64
+	db.Count(&total)
+
+	var events []models.SearchOutbox
+	err := db.Offset((page - 1) * limit).
+		Limit(limit).
+		Order("id ASC").
+		Find(&events).Error
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
+		"pages":  (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// RetryEvent puts one dead-lettered event back in the queue
+func (h *SearchOutboxHandler) RetryEvent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	requeued, err := indexer.Requeue(h.db, uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to requeue event"})
+		return
+	}
+
+	if requeued == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dead event not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Event requeued successfully"})
+}
+
+// RetryAll puts every dead-lettered event back in the queue, e.g. after an Elasticsearch outage
+func (h *SearchOutboxHandler) RetryAll(c *gin.Context) {
+	requeued, err := indexer.Requeue(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to requeue events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Events requeued successfully",
+		"requeued": requeued,
+	})
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"freescholar-backend/config"
+	"freescholar-backend/internal/indexer"
+	"freescholar-backend/internal/models"
+	"freescholar-backend/internal/trash"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TrashHandler handles deleted publications awaiting purge
+type TrashHandler struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+// NewTrashHandler creates a new trash handler
+func NewTrashHandler(db *gorm.DB, cfg *config.Config) *TrashHandler {
+	return &TrashHandler{
+		db:     db,
+		config: cfg,
+	}
+}
+
+// TrashedPublication is a deleted publication and when it will be purged
+type TrashedPublication struct {
+	models.Publication
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// GetTrash handles listing deleted publications, most recently deleted first.
+// Admins see every deleted publication, other users only their own.
+func (h *TrashHandler) GetTrash(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	page, limit := citationPagination(c)
+
+	// Merged duplicates are deleted too, but live on in the publication they were merged into
+	query := h.db.Unscoped().Model(&models.Publication{}).
+		Where("deleted_at IS NOT NULL").
+		Where("id NOT IN (?)", h.db.Model(&models.PublicationRedirect{}).Select("from_id"))
+	if !user.HasRole(models.RoleAdmin) {
+		query = query.Where("owner_id = ?", user.ID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count publications"})
+		return
+	}
+
+	var publications []models.Publication
+	if err := query.Order("deleted_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&publications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch publications"})
+		return
+	}
+
+	retention := trash.Retention(h.config.Trash)
+	items := make([]TrashedPublication, len(publications))
+	for i, publication := range publications {
+		items[i] = TrashedPublication{
+			Publication: publication,
+			PurgeAt:     publication.DeletedAt.Time.Add(retention),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+		"pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// RestorePublication handles taking a publication out of the trash. Its
+// authors, keywords and references were kept, so it only needs its citation
+// counts updated and to be indexed again.
+func (h *TrashHandler) RestorePublication(c *gin.Context) {
+	var publication models.Publication
+	if err := h.db.Unscoped().Where("deleted_at IS NOT NULL").First(&publication, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found in the trash"})
+		return
+	}
+
+	// Merged duplicates gave their associations to the surviving publication
+	if target, ok := models.ResolveRedirect(h.db, publication.ID); ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "Publication was merged into publication " + strconv.Itoa(int(target)), "publication_id": target})
+		return
+	}
+
+	// Start a transaction
+	tx := h.db.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	if err := tx.Unscoped().Model(&publication).Update("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore publication"})
+		return
+	}
+	publication.DeletedAt = gorm.DeletedAt{}
+
+	// References to its DOI added meanwhile, and the publications it cites
+	if err := linkCitations(tx, publication); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link citations"})
+		return
+	}
+	cited, err := models.CitedIDs(tx, publication.ID)
+	if err == nil {
+		err = models.RecountCitations(tx, append(cited, publication.ID)...)
+	}
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update citation counts"})
+		return
+	}
+
+	// Schedule indexing of the publication and the publications it cites
+	if err := indexer.IndexPublications(tx, append(cited, publication.ID)...); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule indexing"})
+		return
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	h.db.Preload("Authors").Preload("Keywords").First(&publication, publication.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Publication restored successfully",
+		"publication": publication,
+	})
+}
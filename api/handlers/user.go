@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"freescholar-backend/config"
+	"freescholar-backend/internal/auth"
 	"freescholar-backend/internal/models"
+	"freescholar-backend/pkg/mailer"
 	"freescholar-backend/pkg/redis"
 
 	"github.com/gin-gonic/gin"
@@ -17,14 +22,18 @@ import (
 type UserHandler struct {
 	db          *gorm.DB
 	redisClient *redis.Client
+	sessions    *auth.SessionStore
+	mailer      *mailer.Mailer
 	config      *config.Config
 }
 
 // NewUserHandler creates a new user handler
-func NewUserHandler(db *gorm.DB, redisClient *redis.Client, cfg *config.Config) *UserHandler {
+func NewUserHandler(db *gorm.DB, redisClient *redis.Client, sessions *auth.SessionStore, mail *mailer.Mailer, cfg *config.Config) *UserHandler {
 	return &UserHandler{
 		db:          db,
 		redisClient: redisClient,
+		sessions:    sessions,
+		mailer:      mail,
 		config:      cfg,
 	}
 }
@@ -52,7 +61,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Create new user
+	// Create new user; the email address stays unverified until the link is followed
 	user := models.User{
 		Username:   input.Username,
 		Email:      input.Email,
@@ -66,7 +75,125 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Registration successful"})
+	// Send verification email
+	if err := h.sendVerificationEmail(user); err != nil {
+		log.Printf("Failed to queue verification email: %v", err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Registration successful, please check your email to verify your address"})
+}
+
+// VerifyEmail confirms a user's email address using the signed link sent by email
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	token := c.Param("token")
+
+	// Validate the token
+	parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(h.config.JWT.Secret), nil
+	})
+
+	if err != nil || !parsedToken.Valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+
+	// Check token type
+	claims, ok := parsedToken.Claims.(jwt.MapClaims)
+	if !ok || claims["type"] != auth.TokenTypeEmailVerify {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token type"})
+		return
+	}
+
+	userID, ok := claims["sub"].(float64)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	// Find user
+	var user models.User
+	if err := h.db.First(&user, uint(userID)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// The link only verifies the address it was sent to
+	if claims["email"] != user.Email {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+
+	if user.IsEmailVerified() {
+		c.JSON(http.StatusOK, gin.H{"message": "Email address already verified"})
+		return
+	}
+
+	if err := h.db.Model(&user).Update("email_verified_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email address verified successfully"})
+}
+
+// ResendVerification sends a new verification email, at most once per configured interval
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Throttle per address, whether or not it is registered
+	ctx := c.Request.Context()
+	interval := time.Duration(h.config.Auth.VerifyResendInterval) * time.Second
+	allowed, err := h.redisClient.SetNX(ctx, "verify_resend:"+strings.ToLower(input.Email), true, interval).Result()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+		return
+	}
+
+	if !allowed {
+		retryAfter, _ := h.redisClient.TTL(ctx, "verify_resend:"+strings.ToLower(input.Email)).Result()
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Please wait before requesting another verification email"})
+		return
+	}
+
+	// Don't reveal whether the email exists or is already verified
+	var user models.User
+	result := h.db.Where("email = ?", input.Email).First(&user)
+	if result.RowsAffected > 0 && !user.IsEmailVerified() {
+		if err := h.sendVerificationEmail(user); err != nil {
+			log.Printf("Failed to queue verification email: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to send verification email, please try again later"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If your email is registered and unverified, you will receive a verification link"})
+}
+
+// sendVerificationEmail queues an email with a signed verification link
+func (h *UserHandler) sendVerificationEmail(user models.User) error {
+	ttl := time.Duration(h.config.Auth.VerifyTokenTTL) * time.Hour
+
+	token, err := auth.GenerateEmailVerifyToken(h.config.JWT.Secret, user.ID, user.Email, ttl)
+	if err != nil {
+		return err
+	}
+
+	return h.mailer.SendTemplate(user.Email, "verify_email", map[string]interface{}{
+		"Username":  user.Username,
+		"VerifyURL": h.mailer.SiteURL() + "/verify/" + token,
+		"TTLHours":  h.config.Auth.VerifyTokenTTL,
+	})
 }
 
 // Login handles user login
@@ -92,45 +219,99 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// Refuse unverified accounts when the policy requires it
+	if !user.IsEmailVerified() && h.config.Auth.UnverifiedAccess == auth.UnverifiedDeny {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Please verify your email address before logging in"})
+		return
+	}
+
 	// Update last login time
 	now := time.Now()
 	h.db.Model(&user).Update("last_login", now)
 
-	// Generate JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": user.ID,
-		"exp": time.Now().Add(time.Hour * 24 * 7).Unix(), // 1 week
-	})
+	// Start a session for this device
+	ctx := c.Request.Context()
+	session, refreshToken, err := h.sessions.Create(ctx, user.ID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
 
-	// Sign and get the complete encoded token as a string
-	tokenString, err := token.SignedString([]byte(h.config.JWT.Secret))
+	// Generate access token bound to the session
+	tokenString, expiresAt, err := auth.GenerateAccessToken(h.config.JWT.Secret, user.ID, session.ID, user.IsEmailVerified(), h.accessTokenTTL())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	// Store token in Redis with user ID as key
+	// Return tokens to client
+	c.JSON(http.StatusOK, gin.H{
+		"token":         tokenString,
+		"refresh_token": refreshToken,
+		"expires_at":    expiresAt,
+		"session_id":    session.ID,
+		"user": gin.H{
+			"id":            user.ID,
+			"username":      user.Username,
+			"email":         user.Email,
+			"emailVerified": user.IsEmailVerified(),
+			"lastLogin":     now,
+		},
+	})
+}
+
+// RefreshToken exchanges a refresh token for a new access token and a new refresh token
+func (h *UserHandler) RefreshToken(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Rotate the refresh token; reusing an old one revokes the whole session
 	ctx := c.Request.Context()
-	err = h.redisClient.Set(ctx, 
-		"user_tokens:"+tokenString, 
-		user.ID, 
-		time.Hour*24*7, // 1 week
-	).Err()
-	
+	session, refreshToken, err := h.sessions.Rotate(ctx, input.RefreshToken, c.ClientIP())
+	switch err {
+	case nil:
+	case auth.ErrRefreshTokenReused:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used; the session has been revoked"})
+		return
+	case auth.ErrInvalidRefreshToken:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	// Reload the user so the new token reflects a freshly verified email
+	var user models.User
+	if err := h.db.First(&user, session.UserID).Error; err != nil {
+		h.sessions.Revoke(ctx, session.UserID, session.ID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !user.IsEmailVerified() && h.config.Auth.UnverifiedAccess == auth.UnverifiedDeny {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Please verify your email address first"})
+		return
+	}
+
+	// Generate access token bound to the session
+	tokenString, expiresAt, err := auth.GenerateAccessToken(h.config.JWT.Secret, user.ID, session.ID, user.IsEmailVerified(), h.accessTokenTTL())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	// Return token to client
 	c.JSON(http.StatusOK, gin.H{
-		"token": tokenString,
-		"user": gin.H{
-			"id":        user.ID,
-			"username":  user.Username,
-			"email":     user.Email,
-			"lastLogin": now,
-		},
+		"token":         tokenString,
+		"refresh_token": refreshToken,
+		"expires_at":    expiresAt,
+		"session_id":    session.ID,
 	})
 }
 
@@ -151,7 +332,7 @@ func (h *UserHandler) Logout(c *gin.Context) {
 	err := h.redisClient.Set(ctx, 
 		"blacklist:"+parts, 
 		true, 
-		time.Hour*24*7, // Same as token expiration
+		h.accessTokenTTL(), // Same as token expiration
 	).Err()
 	
 	if err != nil {
@@ -159,9 +340,80 @@ func (h *UserHandler) Logout(c *gin.Context) {
 		return
 	}
 
+	// End the session so its refresh token stops working too
+	userID := c.GetUint("userID")
+	if err := h.sessions.Revoke(ctx, userID, c.GetString("sessionID")); err != nil && err != auth.ErrSessionNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Logout successful"})
 }
 
+// GetSessions lists the current user's active sessions
+func (h *UserHandler) GetSessions(c *gin.Context) {
+	userID := c.GetUint("userID")
+	currentID := c.GetString("sessionID")
+
+	sessions, err := h.sessions.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, gin.H{
+			"id":        session.ID,
+			"device":    session.Device,
+			"ip":        session.IP,
+			"createdAt": session.CreatedAt,
+			"lastSeen":  session.LastSeen,
+			"current":   session.ID == currentID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": result})
+}
+
+// RevokeSession ends one of the current user's sessions
+func (h *UserHandler) RevokeSession(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	err := h.sessions.Revoke(c.Request.Context(), userID, c.Param("id"))
+	if err == auth.ErrSessionNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+// RevokeOtherSessions ends every session of the current user except the one making the request
+func (h *UserHandler) RevokeOtherSessions(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	revoked, err := h.sessions.RevokeOthers(c.Request.Context(), userID, c.GetString("sessionID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Other sessions revoked successfully",
+		"revoked": revoked,
+	})
+}
+
+// accessTokenTTL returns the configured lifetime of access tokens
+func (h *UserHandler) accessTokenTTL() time.Duration {
+	return time.Duration(h.config.JWT.AccessTokenTTL) * time.Minute
+}
+
 // GetProfile returns the current user's profile
 func (h *UserHandler) GetProfile(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
@@ -187,6 +439,8 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 			"id":              user.ID,
 			"username":        user.Username,
 			"email":           user.Email,
+			"emailVerified":   user.IsEmailVerified(),
+			"role":            user.EffectiveRole(),
 			"dateJoined":      user.DateJoined,
 			"lastLogin":       user.LastLogin,
 			"profileImageURL": user.ProfileImageURL,
@@ -225,15 +479,23 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
+	// Accounts that are read-only until they verify their email may only correct it
+	emailOnly := c.GetBool("unverifiedReadOnly")
+	if emailOnly && (input.Email == "" || input.Email == user.Email) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Please verify your email address first"})
+		return
+	}
+
 	// Update user fields
-	updateData := map[string]interface{}{
-		"biography":        input.Biography,
-		"institution":      input.Institution,
-		"profile_image_url": input.ProfileImageURL,
+	updateData := map[string]interface{}{}
+	if !emailOnly {
+		updateData["biography"] = input.Biography
+		updateData["institution"] = input.Institution
+		updateData["profile_image_url"] = input.ProfileImageURL
 	}
 
 	// Only update username if provided and different
-	if input.Username != "" && input.Username != user.Username {
+	if !emailOnly && input.Username != "" && input.Username != user.Username {
 		// Check if username is already taken
 		var existingUser models.User
 		result := h.db.Where("username = ? AND id != ?", input.Username, user.ID).First(&existingUser)
@@ -254,6 +516,9 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 			return
 		}
 		updateData["email"] = input.Email
+
+		// A new address has to be verified again
+		updateData["email_verified_at"] = nil
 	}
 
 	// Update user in database
@@ -262,9 +527,50 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
+	if _, changed := updateData["email"]; changed {
+		user.Email = input.Email
+		if err := h.sendVerificationEmail(user); err != nil {
+			log.Printf("Failed to queue verification email: %v", err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
 }
 
+// SetUserRole changes another user's role (admin only)
+func (h *UserHandler) SetUserRole(c *gin.Context) {
+	id := c.Param("id")
+
+	var input models.UserRole
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Fetch user from database
+	var user models.User
+	if err := h.db.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Keep the legacy admin flag in step with the role
+	updateData := map[string]interface{}{
+		"role":     input.Role,
+		"is_admin": input.Role == models.RoleAdmin,
+	}
+
+	if err := h.db.Model(&user).Updates(updateData).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role updated successfully",
+		"role":    input.Role,
+	})
+}
+
 // RequestPasswordReset initiates the password reset process
 func (h *UserHandler) RequestPasswordReset(c *gin.Context) {
 	var input struct {
@@ -312,11 +618,18 @@ func (h *UserHandler) RequestPasswordReset(c *gin.Context) {
 		return
 	}
 
-	// TODO: Send email with reset link
-	// For now, just return the token in the response
+	// Send email with reset link
+	err = h.mailer.SendTemplate(user.Email, "password_reset", map[string]interface{}{
+		"Username": user.Username,
+		"ResetURL": h.mailer.SiteURL() + "/reset-password/" + tokenString,
+	})
+	if err != nil {
+		// Answer as for unknown emails, so failures don't reveal which are registered
+		log.Printf("Failed to queue password reset email: %v", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "If your email is registered, you will receive a password reset link",
-		"token": tokenString, // In production, this would be sent via email
 	})
 }
 
@@ -393,5 +706,15 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 	// Delete token from Redis
 	h.redisClient.Del(ctx, "password_reset:"+token)
 
+	// Sign out every device that may have been using the old password
+	h.sessions.RevokeOthers(ctx, user.ID, "")
+
+	// Let the owner know in case the change was not theirs
+	if err := h.mailer.SendTemplate(user.Email, "password_changed", map[string]interface{}{
+		"Username": user.Username,
+	}); err != nil {
+		log.Printf("Failed to queue password changed email: %v", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset successfully"})
 }
\ No newline at end of file
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"freescholar-backend/internal/auth"
 	"freescholar-backend/pkg/redis"
 
 	"github.com/gin-gonic/gin"
@@ -13,15 +14,20 @@ import (
 
 // AuthMiddleware handles authentication for protected routes
 type AuthMiddleware struct {
-	jwtSecret   string
-	redisClient *redis.Client
+	jwtSecret        string
+	redisClient      *redis.Client
+	sessions         *auth.SessionStore
+	unverifiedAccess string
 }
 
-// NewAuthMiddleware creates a new instance of the auth middleware
-func NewAuthMiddleware(jwtSecret string, redisClient *redis.Client) *AuthMiddleware {
+// NewAuthMiddleware creates a new instance of the auth middleware.
+// unverifiedAccess is one of the auth.Unverified* policies.
+func NewAuthMiddleware(jwtSecret string, redisClient *redis.Client, sessions *auth.SessionStore, unverifiedAccess string) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwtSecret:   jwtSecret,
-		redisClient: redisClient,
+		jwtSecret:        jwtSecret,
+		redisClient:      redisClient,
+		sessions:         sessions,
+		unverifiedAccess: unverifiedAccess,
 	}
 }
 
@@ -36,72 +42,159 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
-		// Check if the header has the Bearer format
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token}"})
-			c.Abort()
+		if m.authenticate(c, authHeader) {
+			c.Next()
+		}
+	}
+}
+
+// OptionalAuth is a middleware that identifies the user if the request carries
+// a token and lets anonymous requests through. Invalid tokens are still rejected.
+func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader != "" && !m.authenticate(c, authHeader) {
 			return
 		}
 
-		tokenString := parts[1]
+		c.Next()
+	}
+}
 
-		// Check if token is blacklisted in Redis
-		ctx := c.Request.Context()
-		blacklisted, err := m.redisClient.Exists(ctx, "blacklist:"+tokenString).Result()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate token"})
+// authenticate validates the token in the Authorization header and sets the
+// user in the context. It aborts the request and returns false if the token is not valid.
+func (m *AuthMiddleware) authenticate(c *gin.Context, authHeader string) bool {
+	// Check if the header has the Bearer format
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token}"})
+		c.Abort()
+		return false
+	}
+
+	tokenString := parts[1]
+
+	// Check if token is blacklisted in Redis
+	ctx := c.Request.Context()
+	blacklisted, err := m.redisClient.Exists(ctx, "blacklist:"+tokenString).Result()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate token"})
+		c.Abort()
+		return false
+	}
+
+	if blacklisted == 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been invalidated"})
+		c.Abort()
+		return false
+	}
+
+	// Parse and validate the token
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// Validate the signing method
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(m.jwtSecret), nil
+	})
+
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		c.Abort()
+		return false
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		// Check if token is expired
+		if exp, ok := claims["exp"].(float64); ok {
+			if time.Unix(int64(exp), 0).Before(time.Now()) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has expired"})
+				c.Abort()
+				return false
+			}
+		}
+
+		// Only access tokens may be used here, not reset or refresh tokens
+		if claims["type"] != auth.TokenTypeAccess {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token type"})
 			c.Abort()
-			return
+			return false
 		}
 
-		if blacklisted == 1 {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been invalidated"})
+		// Set user ID in context
+		userID, ok := claims["sub"].(float64)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
 			c.Abort()
-			return
+			return false
 		}
 
-		// Parse and validate the token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate the signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(m.jwtSecret), nil
-		})
+		sessionID, ok := claims["sid"].(string)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+			c.Abort()
+			return false
+		}
 
+		// Check that the session has not been revoked, recording activity for the session list
+		active, err := m.sessions.Touch(ctx, sessionID, c.ClientIP())
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate token"})
 			c.Abort()
-			return
+			return false
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// Check if token is expired
-			if exp, ok := claims["exp"].(float64); ok {
-				if time.Unix(int64(exp), 0).Before(time.Now()) {
-					c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has expired"})
-					c.Abort()
-					return
-				}
-			}
-
-			// Set user ID in context
-			userID, ok := claims["sub"].(float64)
-			if !ok {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-				c.Abort()
-				return
-			}
+		if !active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			c.Abort()
+			return false
+		}
 
-			c.Set("userID", uint(userID))
-			
-			// Continue to the next handler
-			c.Next()
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		// Apply the policy for accounts that have not verified their email
+		verified, _ := claims["ver"].(bool)
+		if !verified && !m.unverifiedAllowed(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Please verify your email address first"})
 			c.Abort()
-			return
+			return false
 		}
+
+		c.Set("userID", uint(userID))
+		c.Set("sessionID", sessionID)
+		c.Set("emailVerified", verified)
+
+		return true
 	}
-}
\ No newline at end of file
+
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	c.Abort()
+	return false
+}
+
+// unverifiedWrites are the routes an unverified account may still change under
+// the read_only policy: signing out its sessions and correcting a mistyped email
+var unverifiedWrites = map[string]bool{
+	http.MethodDelete + " /api/user/sessions":     true,
+	http.MethodDelete + " /api/user/sessions/:id": true,
+	http.MethodPut + " /api/user/profile":         true,
+}
+
+// unverifiedAllowed reports whether an unverified account may make the request
+func (m *AuthMiddleware) unverifiedAllowed(c *gin.Context) bool {
+	switch m.unverifiedAccess {
+	case auth.UnverifiedAllow:
+		return true
+	case auth.UnverifiedDeny:
+		return false
+	}
+
+	method := c.Request.Method
+	if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+		return true
+	}
+	if unverifiedWrites[method+" "+c.FullPath()] {
+		// Handlers limit what such accounts may change, e.g. only the email of the profile
+		c.Set("unverifiedReadOnly", true)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"freescholar-backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUnverifiedAllowed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		policy string
+		method string
+		path   string
+		want   bool
+	}{
+		{auth.UnverifiedAllow, http.MethodPost, "/api/publication", true},
+		{auth.UnverifiedDeny, http.MethodGet, "/api/publication/1", false},
+		{auth.UnverifiedDeny, http.MethodDelete, "/api/user/sessions", false},
+		{auth.UnverifiedReadOnly, http.MethodGet, "/api/publication/1", true},
+		{auth.UnverifiedReadOnly, http.MethodPost, "/api/publication", false},
+		{auth.UnverifiedReadOnly, http.MethodPut, "/api/publication/1", false},
+		{auth.UnverifiedReadOnly, http.MethodDelete, "/api/user/sessions", true},
+		{auth.UnverifiedReadOnly, http.MethodDelete, "/api/user/sessions/abc", true},
+		{auth.UnverifiedReadOnly, http.MethodPut, "/api/user/profile", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy+" "+tt.method+" "+tt.path, func(t *testing.T) {
+			m := &AuthMiddleware{unverifiedAccess: tt.policy}
+
+			var got bool
+			router := gin.New()
+			handler := func(c *gin.Context) { got = m.unverifiedAllowed(c) }
+			router.GET("/api/publication/:id", handler)
+			router.POST("/api/publication", handler)
+			router.PUT("/api/publication/:id", handler)
+			router.DELETE("/api/user/sessions", handler)
+			router.DELETE("/api/user/sessions/:id", handler)
+			router.PUT("/api/user/profile", handler)
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+			if got != tt.want {
+				t.Errorf("unverifiedAllowed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"freescholar-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// OwnerResolver looks up the owner of the record addressed by the request
+type OwnerResolver func(db *gorm.DB, c *gin.Context) (uint, error)
+
+// PermissionMiddleware handles authorization for protected routes.
+// It must run after AuthMiddleware.RequireAuth, which sets the user ID.
+type PermissionMiddleware struct {
+	db *gorm.DB
+}
+
+// NewPermissionMiddleware creates a new instance of the permission middleware
+func NewPermissionMiddleware(db *gorm.DB) *PermissionMiddleware {
+	return &PermissionMiddleware{
+		db: db,
+	}
+}
+
+// RequireRole is a middleware that only lets users holding one of the roles through.
+// Admins are always allowed.
+func (m *PermissionMiddleware) RequireRole(roles ...models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := m.currentUser(c)
+		if !ok {
+			return
+		}
+
+		if !user.HasRole(roles...) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to perform this action"})
+			c.Abort()
+			return
+		}
+
+		c.Set("userRole", user.EffectiveRole())
+		c.Next()
+	}
+}
+
+// RequirePermission is a middleware that checks whether the user may perform the
+// action on the record resolved by owner, according to models.User.Can
+func (m *PermissionMiddleware) RequirePermission(action models.Action, owner OwnerResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := m.currentUser(c)
+		if !ok {
+			return
+		}
+
+		ownerID, err := owner(m.db, c)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			}
+			c.Abort()
+			return
+		}
+
+		if !user.Can(action, ownerID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to modify this record"})
+			c.Abort()
+			return
+		}
+
+		c.Set("userRole", user.RoleFor(ownerID))
+		c.Next()
+	}
+}
+
+// currentUser loads the authenticated user, aborting the request if there is none
+func (m *PermissionMiddleware) currentUser(c *gin.Context) (*models.User, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Abort()
+		return nil, false
+	}
+
+	var user models.User
+	if err := m.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		c.Abort()
+		return nil, false
+	}
+
+	return &user, true
+}
+
+// PublicationOwner resolves the owner of the publication in the :id path parameter
+func PublicationOwner(db *gorm.DB, c *gin.Context) (uint, error) {
+	var publication models.Publication
+	if err := db.Select("id", "owner_id").First(&publication, c.Param("id")).Error; err != nil {
+		return 0, err
+	}
+	return publication.OwnerID, nil
+}
+
+// TrashedPublicationOwner resolves the owner of the deleted publication in the :id path parameter
+func TrashedPublicationOwner(db *gorm.DB, c *gin.Context) (uint, error) {
+	var publication models.Publication
+	if err := db.Unscoped().Select("id", "owner_id").Where("deleted_at IS NOT NULL").First(&publication, c.Param("id")).Error; err != nil {
+		return 0, err
+	}
+	return publication.OwnerID, nil
+}
+
+// FileOwner resolves the owner of the file in the :id path parameter, see models.FileOwnerID
+func FileOwner(db *gorm.DB, c *gin.Context) (uint, error) {
+	var file models.File
+	if err := db.Select("id", "uploader_id", "publication_id").First(&file, c.Param("id")).Error; err != nil {
+		return 0, err
+	}
+	return models.FileOwnerID(db, file)
+}
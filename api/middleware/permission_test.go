@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+
+	"freescholar-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+const (
+	adminID   = 1
+	curatorID = 2
+	ownerID   = 3
+	readerID  = 4
+
+	publicationID = 10
+)
+
+// fakeTables serves the lookups by primary key the middleware makes, so the
+// tests need no MySQL server
+var fakeTables = map[string]map[int64]map[string]driver.Value{
+	"users": {
+		adminID:   {"id": int64(adminID), "role": "admin", "is_admin": false},
+		curatorID: {"id": int64(curatorID), "role": "curator", "is_admin": false},
+		ownerID:   {"id": int64(ownerID), "role": "reader", "is_admin": false},
+		readerID:  {"id": int64(readerID), "role": "reader", "is_admin": false},
+	},
+	"publications": {
+		publicationID: {"id": int64(publicationID), "owner_id": int64(ownerID)},
+	},
+}
+
+var fromTable = regexp.MustCompile("FROM `(\\w+)`")
+
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	match := fromTable.FindStringSubmatch(query)
+	if match == nil || len(args) == 0 {
+		return nil, fmt.Errorf("unexpected query %q", query)
+	}
+	id, err := strconv.ParseInt(fmt.Sprint(args[0].Value), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	row, ok := fakeTables[match[1]][id]
+	if !ok {
+		return &fakeRows{}, nil
+	}
+	rows := &fakeRows{values: [][]driver.Value{{}}}
+	for column := range row {
+		rows.columns = append(rows.columns, column)
+	}
+	sort.Strings(rows.columns)
+	for _, column := range rows.columns {
+		rows.values[0] = append(rows.values[0], row[column])
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// newPermissionRouter serves PUT /publication/:id behind the middleware
+// built by route, taking the user ID from the X-User header
+func newPermissionRouter(t *testing.T, route func(m *PermissionMiddleware) gin.HandlerFunc) *gin.Engine {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(fakeConnector{}),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// Stands in for AuthMiddleware.RequireAuth
+	authenticate := func(c *gin.Context) {
+		if id, err := strconv.Atoi(c.GetHeader("X-User")); err == nil && id != 0 {
+			c.Set("userID", uint(id))
+		}
+	}
+	router.PUT("/publication/:id", authenticate, route(NewPermissionMiddleware(db)), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func request(router *gin.Engine, publicationID, userID int) int {
+	req := httptest.NewRequest(http.MethodPut, "/publication/"+strconv.Itoa(publicationID), nil)
+	req.Header.Set("X-User", strconv.Itoa(userID))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID int
+		want   int
+	}{
+		{"anonymous", 0, http.StatusUnauthorized},
+		{"unknown user", 99, http.StatusUnauthorized},
+		{"reader", readerID, http.StatusForbidden},
+		{"owner", ownerID, http.StatusForbidden},
+		{"curator", curatorID, http.StatusOK},
+		{"admin", adminID, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newPermissionRouter(t, func(m *PermissionMiddleware) gin.HandlerFunc {
+				return m.RequireRole(models.RoleCurator)
+			})
+			if got := request(router, publicationID, tt.userID); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name          string
+		action        models.Action
+		publicationID int
+		userID        int
+		want          int
+	}{
+		{"reader updates", models.ActionUpdate, publicationID, readerID, http.StatusForbidden},
+		{"reader deletes", models.ActionDelete, publicationID, readerID, http.StatusForbidden},
+		{"owner updates", models.ActionUpdate, publicationID, ownerID, http.StatusOK},
+		{"owner deletes", models.ActionDelete, publicationID, ownerID, http.StatusOK},
+		{"curator updates", models.ActionUpdate, publicationID, curatorID, http.StatusOK},
+		{"curator deletes", models.ActionDelete, publicationID, curatorID, http.StatusForbidden},
+		{"admin deletes", models.ActionDelete, publicationID, adminID, http.StatusOK},
+		{"missing publication", models.ActionUpdate, 404, adminID, http.StatusNotFound},
+		{"anonymous", models.ActionUpdate, publicationID, 0, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newPermissionRouter(t, func(m *PermissionMiddleware) gin.HandlerFunc {
+				return m.RequirePermission(tt.action, PublicationOwner)
+			})
+			if got := request(router, tt.publicationID, tt.userID); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
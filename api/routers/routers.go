@@ -1,10 +1,17 @@
 package routers
 
 import (
+	"time"
+
 	"freescholar-backend/api/handlers"
 	"freescholar-backend/api/middleware"
 	"freescholar-backend/config"
+	"freescholar-backend/internal/auth"
+	"freescholar-backend/internal/media"
+	"freescholar-backend/internal/metadata"
+	"freescholar-backend/internal/models"
 	"freescholar-backend/pkg/elasticsearch"
+	"freescholar-backend/pkg/mailer"
 	"freescholar-backend/pkg/redis"
 
 	"github.com/gin-contrib/cors"
@@ -13,7 +20,7 @@ import (
 )
 
 // SetupRouter configures the Gin router
-func SetupRouter(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, esClient *elasticsearch.Client) *gin.Engine {
+func SetupRouter(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, esClient *elasticsearch.Client, mail *mailer.Mailer, mediaStore *media.Store) *gin.Engine {
 	// Set Gin mode
 	if cfg.Server.Debug {
 		gin.SetMode(gin.DebugMode)
@@ -36,18 +43,27 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, esC
 	router.Use(cors.New(corsConfig))
 
 	// Initialize handlers
-	userHandler := handlers.NewUserHandler(db, redisClient, cfg)
+	sessionStore := auth.NewSessionStore(redisClient, time.Duration(cfg.JWT.RefreshTokenTTL)*time.Hour)
+	userHandler := handlers.NewUserHandler(db, redisClient, sessionStore, mail, cfg)
 	publicationHandler := handlers.NewPublicationHandler(db, esClient, cfg)
-	//authorHandler := handlers.NewAuthorHandler(db, esClient, cfg)
+	citationHandler := handlers.NewCitationHandler(db, cfg)
+	bibliographyHandler := handlers.NewBibliographyHandler(db, cfg)
+	crossref := metadata.NewCrossref(cfg.Metadata.CrossrefURL, cfg.Metadata.Mailto, time.Duration(cfg.Metadata.Timeout)*time.Second)
+	metadataHandler := handlers.NewMetadataHandler(db, crossref, cfg)
+	authorHandler := handlers.NewAuthorHandler(db, esClient, cfg)
+	searchOutboxHandler := handlers.NewSearchOutboxHandler(db, cfg)
+	duplicateHandler := handlers.NewDuplicateHandler(db, cfg)
+	trashHandler := handlers.NewTrashHandler(db, cfg)
+	filesHandler := handlers.NewFilesHandler(db, mediaStore, cfg)
 	//scholarPortalHandler := handlers.NewScholarPortalHandler(db, cfg)
 	//relationHandler := handlers.NewRelationHandler(db, cfg)
 	//searchListHandler := handlers.NewSearchListHandler(db, esClient, cfg)
 	//messageCenterHandler := handlers.NewMessageCenterHandler(db, cfg)
-	//filesHandler := handlers.NewFilesHandler(db, cfg)
 	//serializationHandler := handlers.NewSerializationHandler(db, cfg)
 
 	// Set up auth middleware
-	authMiddleware := middleware.NewAuthMiddleware(cfg.JWT.Secret, redisClient)
+	authMiddleware := middleware.NewAuthMiddleware(cfg.JWT.Secret, redisClient, sessionStore, cfg.Auth.UnverifiedAccess)
+	permissionMiddleware := middleware.NewPermissionMiddleware(db)
 
 	// API routes
 	api := router.Group("/api")
@@ -57,11 +73,18 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, esC
 		{
 			userRoutes.POST("/register", userHandler.Register)
 			userRoutes.POST("/login", userHandler.Login)
+			userRoutes.POST("/refresh", userHandler.RefreshToken)
+			userRoutes.GET("/verify/:token", userHandler.VerifyEmail)
+			userRoutes.POST("/verify/resend", userHandler.ResendVerification)
 			userRoutes.GET("/logout", authMiddleware.RequireAuth(), userHandler.Logout)
 			userRoutes.GET("/profile", authMiddleware.RequireAuth(), userHandler.GetProfile)
 			userRoutes.PUT("/profile", authMiddleware.RequireAuth(), userHandler.UpdateProfile)
+			userRoutes.GET("/sessions", authMiddleware.RequireAuth(), userHandler.GetSessions)
+			userRoutes.DELETE("/sessions", authMiddleware.RequireAuth(), userHandler.RevokeOtherSessions)
+			userRoutes.DELETE("/sessions/:id", authMiddleware.RequireAuth(), userHandler.RevokeSession)
 			userRoutes.POST("/reset-password", userHandler.RequestPasswordReset)
 			userRoutes.POST("/reset-password/:token", userHandler.ResetPassword)
+			userRoutes.PUT("/:id/role", authMiddleware.RequireAuth(), permissionMiddleware.RequireRole(models.RoleAdmin), userHandler.SetUserRole)
 		}
 	
 		
@@ -69,21 +92,60 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, esC
 		publicationRoutes := api.Group("/publication")
 		{
 			publicationRoutes.GET("", publicationHandler.GetPublications)
+			publicationRoutes.GET("/suggest", publicationHandler.SuggestPublications)
+			publicationRoutes.GET("/export", bibliographyHandler.ExportPublications)
+			publicationRoutes.GET("/lookup", authMiddleware.RequireAuth(), metadataHandler.LookupDOI)
+			publicationRoutes.GET("/trash", authMiddleware.RequireAuth(), trashHandler.GetTrash)
 			publicationRoutes.GET("/:id", publicationHandler.GetPublication)
+			publicationRoutes.GET("/:id/bibtex", bibliographyHandler.GetPublicationBibTeX)
+			publicationRoutes.GET("/:id/cite", bibliographyHandler.CitePublication)
+			publicationRoutes.GET("/:id/related", publicationHandler.GetRelatedPublications)
+			publicationRoutes.GET("/:id/references", citationHandler.GetReferences)
+			publicationRoutes.GET("/:id/cited-by", citationHandler.GetCitedBy)
+			publicationRoutes.GET("/:id/pdf", authMiddleware.OptionalAuth(), filesHandler.GetPublicationPDF)
+			publicationRoutes.GET("/:id/revisions", publicationHandler.GetRevisions)
+			publicationRoutes.GET("/:id/revisions/:revision", publicationHandler.GetRevision)
 			publicationRoutes.POST("", authMiddleware.RequireAuth(), publicationHandler.CreatePublication)
-			publicationRoutes.PUT("/:id", authMiddleware.RequireAuth(), publicationHandler.UpdatePublication)
-			publicationRoutes.DELETE("/:id", authMiddleware.RequireAuth(), publicationHandler.DeletePublication)
+			publicationRoutes.POST("/import", authMiddleware.RequireAuth(), bibliographyHandler.ImportPublications)
+			publicationRoutes.POST("/doi", authMiddleware.RequireAuth(), metadataHandler.CreateFromDOI)
+			publicationRoutes.PUT("/:id", authMiddleware.RequireAuth(), permissionMiddleware.RequirePermission(models.ActionUpdate, middleware.PublicationOwner), publicationHandler.UpdatePublication)
+			publicationRoutes.PATCH("/:id", authMiddleware.RequireAuth(), permissionMiddleware.RequirePermission(models.ActionUpdate, middleware.PublicationOwner), publicationHandler.UpdatePublication)
+			publicationRoutes.POST("/:id/revisions/:revision/restore", authMiddleware.RequireAuth(), permissionMiddleware.RequirePermission(models.ActionUpdate, middleware.PublicationOwner), publicationHandler.RestoreRevision)
+			publicationRoutes.DELETE("/:id", authMiddleware.RequireAuth(), permissionMiddleware.RequirePermission(models.ActionDelete, middleware.PublicationOwner), publicationHandler.DeletePublication)
+			publicationRoutes.PUT("/:id/references", authMiddleware.RequireAuth(), permissionMiddleware.RequirePermission(models.ActionUpdate, middleware.PublicationOwner), citationHandler.SetReferences)
+			publicationRoutes.POST("/:id/pdf", authMiddleware.RequireAuth(), permissionMiddleware.RequirePermission(models.ActionUpdate, middleware.PublicationOwner), filesHandler.UploadPDF)
+			publicationRoutes.POST("/:id/restore", authMiddleware.RequireAuth(), permissionMiddleware.RequirePermission(models.ActionDelete, middleware.TrashedPublicationOwner), trashHandler.RestorePublication)
+			publicationRoutes.POST("/:id/merge", authMiddleware.RequireAuth(), permissionMiddleware.RequireRole(models.RoleCurator), publicationHandler.MergePublications)
 		}
-		/*
+
 		// Author routes
 		authorRoutes := api.Group("/author")
 		{
 			authorRoutes.GET("", authorHandler.GetAuthors)
 			authorRoutes.GET("/:id", authorHandler.GetAuthor)
 			authorRoutes.POST("", authMiddleware.RequireAuth(), authorHandler.CreateAuthor)
-			authorRoutes.PUT("/:id", authMiddleware.RequireAuth(), authorHandler.UpdateAuthor)
+			authorRoutes.PUT("/:id", authMiddleware.RequireAuth(), permissionMiddleware.RequireRole(models.RoleCurator), authorHandler.UpdateAuthor)
+			authorRoutes.DELETE("/:id", authMiddleware.RequireAuth(), permissionMiddleware.RequireRole(models.RoleCurator), authorHandler.DeleteAuthor)
+			authorRoutes.POST("/:id/merge", authMiddleware.RequireAuth(), permissionMiddleware.RequireRole(models.RoleCurator), authorHandler.MergeAuthors)
+		}
+
+		// Admin routes
+		adminRoutes := api.Group("/admin", authMiddleware.RequireAuth(), permissionMiddleware.RequireRole(models.RoleAdmin))
+		{
+			adminRoutes.GET("/search-outbox", searchOutboxHandler.GetEvents)
+			adminRoutes.POST("/search-outbox/retry", searchOutboxHandler.RetryAll)
+			adminRoutes.POST("/search-outbox/:id/retry", searchOutboxHandler.RetryEvent)
+			adminRoutes.GET("/duplicates", duplicateHandler.GetDuplicates)
 		}
 
+		// Files routes
+		filesRoutes := api.Group("/media")
+		{
+			filesRoutes.GET("/:id", authMiddleware.OptionalAuth(), filesHandler.GetFile)
+			filesRoutes.PUT("/:id/visibility", authMiddleware.RequireAuth(), permissionMiddleware.RequirePermission(models.ActionUpdate, middleware.FileOwner), filesHandler.SetFileVisibility)
+			filesRoutes.POST("/:id/share", authMiddleware.RequireAuth(), permissionMiddleware.RequirePermission(models.ActionUpdate, middleware.FileOwner), filesHandler.ShareFile)
+		}
+		/*
 		// ScholarPortal routes
 		scholarRoutes := api.Group("/ScholarPortal")
 		{
@@ -118,13 +180,6 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, esC
 			messageRoutes.PUT("/:id/read", authMiddleware.RequireAuth(), messageCenterHandler.MarkAsRead)
 		}
 
-		// Files routes
-		filesRoutes := api.Group("/media")
-		{
-			filesRoutes.POST("/upload", authMiddleware.RequireAuth(), filesHandler.UploadFile)
-			filesRoutes.GET("/:filename", filesHandler.GetFile)
-		}
-
 		// Serialization routes
 		serialRoutes := api.Group("/serialization")
 		{
@@ -134,8 +189,8 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, esC
 		*/
 	}
 	
-	// Serve static files
-	router.Static("/media", cfg.Media.Root)
+	// Serve stored files from whichever storage driver is configured, checking their visibility
+	router.GET("/media/*path", authMiddleware.OptionalAuth(), filesHandler.ServeMedia)
 
 	return router
 }
\ No newline at end of file
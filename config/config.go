@@ -17,7 +17,12 @@ type Config struct {
 	ES       ESConfig       `mapstructure:"elasticsearch"`
 	Email    EmailConfig    `mapstructure:"email"`
 	JWT      JWTConfig      `mapstructure:"jwt"`
+	Auth     AuthConfig     `mapstructure:"auth"`
 	Media    MediaConfig    `mapstructure:"media"`
+	Import   ImportConfig   `mapstructure:"import"`
+	Metadata MetadataConfig `mapstructure:"metadata"`
+	Trash    TrashConfig    `mapstructure:"trash"`
+	Fulltext FulltextConfig `mapstructure:"fulltext"`
 }
 
 // ServerConfig holds all server related configuration
@@ -49,26 +54,103 @@ type RedisConfig struct {
 // ESConfig holds all elasticsearch related configuration
 type ESConfig struct {
 	URL string `mapstructure:"url"`
+
+	// Search outbox worker settings
+	OutboxBatchSize    int `mapstructure:"outbox_batch_size"`
+	OutboxPollInterval int `mapstructure:"outbox_poll_interval"` // seconds
+	OutboxMaxAttempts  int `mapstructure:"outbox_max_attempts"`
+	OutboxRetention    int `mapstructure:"outbox_retention"` // hours processed events are kept for reindex catch-up
+
+	// Search result highlighting
+	HighlightFragmentSize int `mapstructure:"highlight_fragment_size"` // characters
+	HighlightFragments    int `mapstructure:"highlight_fragments"`
+
+	// Searches with fewer hits than this get a "did you mean" correction
+	SuggestBelow int `mapstructure:"suggest_below"`
 }
 
 // EmailConfig holds email sending configuration
 type EmailConfig struct {
-	Host     string `mapstructure:"host"`
-	Port     int    `mapstructure:"port"`
-	User     string `mapstructure:"user"`
-	Password string `mapstructure:"password"`
-	UseTLS   bool   `mapstructure:"use_tls"`
+	Host       string `mapstructure:"host"`
+	Port       int    `mapstructure:"port"`
+	User       string `mapstructure:"user"`
+	Password   string `mapstructure:"password"`
+	UseTLS     bool   `mapstructure:"use_tls"`
+	Driver     string `mapstructure:"driver"` // "smtp" or "outbox"
+	From       string `mapstructure:"from"`
+	FromName   string `mapstructure:"from_name"`
+	OutboxDir  string `mapstructure:"outbox_dir"`
+	SiteURL    string `mapstructure:"site_url"` // base URL used for links in emails
+	QueueSize  int    `mapstructure:"queue_size"`
+	Workers    int    `mapstructure:"workers"`
+	MaxRetries int    `mapstructure:"max_retries"`
 }
 
 // JWTConfig holds jwt token configuration
 type JWTConfig struct {
-	Secret string `mapstructure:"secret_key"`
+	Secret          string `mapstructure:"secret_key"`
+	AccessTokenTTL  int    `mapstructure:"access_token_ttl"`  // minutes
+	RefreshTokenTTL int    `mapstructure:"refresh_token_ttl"` // hours
+}
+
+// AuthConfig holds account policy configuration
+type AuthConfig struct {
+	// UnverifiedAccess decides what accounts with an unconfirmed email may do:
+	// "allow" everything, "read_only" (no POST/PUT/PATCH/DELETE) or "deny" login
+	UnverifiedAccess     string `mapstructure:"unverified_access"`
+	VerifyTokenTTL       int    `mapstructure:"verify_token_ttl"`       // hours
+	VerifyResendInterval int    `mapstructure:"verify_resend_interval"` // seconds
 }
 
 // MediaConfig holds media file configuration
 type MediaConfig struct {
-	Root string `mapstructure:"root"`
-	URL  string `mapstructure:"url"`
+	Driver        string   `mapstructure:"driver"` // "local" or "s3"
+	Root          string   `mapstructure:"root"`   // directory of the local driver
+	URL           string   `mapstructure:"url"`
+	MaxUploadSize int64    `mapstructure:"max_upload_size"` // bytes per uploaded file
+	PresignTTL    int      `mapstructure:"presign_ttl"`     // seconds; downloads redirect to presigned URLs when set
+	S3            S3Config `mapstructure:"s3"`
+
+	// Signed share links for files that are not public
+	SigningKey  string `mapstructure:"signing_key"`   // defaults to the JWT secret
+	ShareTTL    int    `mapstructure:"share_ttl"`     // seconds a link is valid unless the request asks otherwise
+	ShareMaxTTL int    `mapstructure:"share_max_ttl"` // seconds
+}
+
+// S3Config holds configuration of the S3-compatible storage driver
+type S3Config struct {
+	Endpoint  string `mapstructure:"endpoint"`
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+}
+
+// ImportConfig holds limits of bibliography import and export
+type ImportConfig struct {
+	MaxSize      int64 `mapstructure:"max_size"` // bytes per uploaded document
+	MaxEntries   int   `mapstructure:"max_entries"`
+	ExportMaxIDs int   `mapstructure:"export_max_ids"`
+}
+
+// MetadataConfig holds configuration of DOI metadata lookup
+type MetadataConfig struct {
+	CrossrefURL string `mapstructure:"crossref_url"`
+	Mailto      string `mapstructure:"mailto"`
+	Timeout     int    `mapstructure:"timeout"` // seconds
+}
+
+// TrashConfig holds configuration of the publication trash
+type TrashConfig struct {
+	RetentionDays int `mapstructure:"retention_days"` // days before deleted publications are purged
+	PurgeInterval int `mapstructure:"purge_interval"` // minutes between purge runs
+}
+
+// FulltextConfig holds configuration of PDF text extraction
+type FulltextConfig struct {
+	PollInterval int `mapstructure:"poll_interval"` // seconds
+	BatchSize    int `mapstructure:"batch_size"`
+	MaxChars     int `mapstructure:"max_chars"` // characters of text kept per file
 }
 
 // Secrets structure for secrets.json
@@ -78,6 +160,9 @@ type Secrets struct {
 	EmailUser        string `json:"EMAIL_HOST_USER"`
 	EmailPassword    string `json:"EMAIL_HOST_PASSWORD"`
 	SecretKey        string `json:"SECRET_KEY"`
+	S3AccessKey      string `json:"S3_ACCESS_KEY"`
+	S3SecretKey      string `json:"S3_SECRET_KEY"`
+	MediaSigningKey  string `json:"MEDIA_SIGNING_KEY"`
 }
 
 // LoadConfig loads configuration from config.yaml and secrets.json files
@@ -158,15 +243,63 @@ func setDefaults() {
 
 	// Elasticsearch defaults
 	viper.SetDefault("elasticsearch.url", "http://localhost:9200")
+	viper.SetDefault("elasticsearch.outbox_batch_size", 100)
+	viper.SetDefault("elasticsearch.outbox_poll_interval", 2)
+	viper.SetDefault("elasticsearch.outbox_max_attempts", 10)
+	viper.SetDefault("elasticsearch.outbox_retention", 72)
+	viper.SetDefault("elasticsearch.highlight_fragment_size", 150)
+	viper.SetDefault("elasticsearch.highlight_fragments", 3)
+	viper.SetDefault("elasticsearch.suggest_below", 5)
 
 	// Email defaults
 	viper.SetDefault("email.host", "smtp.qq.com")
 	viper.SetDefault("email.port", 25)
 	viper.SetDefault("email.use_tls", false)
+	viper.SetDefault("email.driver", "smtp")
+	viper.SetDefault("email.from_name", "FreeScholar")
+	viper.SetDefault("email.outbox_dir", "./outbox")
+	viper.SetDefault("email.site_url", "http://localhost:8080")
+	viper.SetDefault("email.queue_size", 100)
+	viper.SetDefault("email.workers", 2)
+	viper.SetDefault("email.max_retries", 3)
+
+	// JWT defaults
+	viper.SetDefault("jwt.access_token_ttl", 15)
+	viper.SetDefault("jwt.refresh_token_ttl", 24*30)
+
+	// Auth defaults
+	viper.SetDefault("auth.unverified_access", "read_only")
+	viper.SetDefault("auth.verify_token_ttl", 48)
+	viper.SetDefault("auth.verify_resend_interval", 60)
 
 	// Media defaults
+	viper.SetDefault("media.driver", "local")
 	viper.SetDefault("media.root", "./media")
 	viper.SetDefault("media.url", "/media/")
+	viper.SetDefault("media.max_upload_size", 50<<20)
+	viper.SetDefault("media.presign_ttl", 0)
+	viper.SetDefault("media.s3.region", "us-east-1")
+	viper.SetDefault("media.share_ttl", 24*60*60)
+	viper.SetDefault("media.share_max_ttl", 7*24*60*60)
+
+	// Import defaults
+	viper.SetDefault("import.max_size", 10<<20)
+	viper.SetDefault("import.max_entries", 1000)
+	viper.SetDefault("import.export_max_ids", 500)
+
+	// Metadata defaults
+	viper.SetDefault("metadata.crossref_url", "https://api.crossref.org")
+	viper.SetDefault("metadata.mailto", "")
+	viper.SetDefault("metadata.timeout", 10)
+
+	// Trash defaults
+	viper.SetDefault("trash.retention_days", 30)
+	viper.SetDefault("trash.purge_interval", 60)
+
+	// Fulltext defaults
+	viper.SetDefault("fulltext.poll_interval", 10)
+	viper.SetDefault("fulltext.batch_size", 10)
+	viper.SetDefault("fulltext.max_chars", 1000000)
 }
 
 // injectSecrets injects sensitive configuration from secrets into viper
@@ -176,4 +309,7 @@ func injectSecrets(secrets *Secrets) {
 	viper.Set("email.user", secrets.EmailUser)
 	viper.Set("email.password", secrets.EmailPassword)
 	viper.Set("jwt.secret_key", secrets.SecretKey)
+	viper.Set("media.s3.access_key", secrets.S3AccessKey)
+	viper.Set("media.s3.secret_key", secrets.S3SecretKey)
+	viper.Set("media.signing_key", secrets.MediaSigningKey)
 }
\ No newline at end of file
@@ -0,0 +1,305 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"freescholar-backend/pkg/redis"
+
+	goredis "github.com/go-redis/redis/v8"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is
+	// presented again; the whole session has been revoked as a precaution
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	// ErrSessionNotFound is returned when a session does not exist or belongs to another user
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// rotateScript swaps the session's current refresh token hash only if the
+// presented one is still current, so concurrent refreshes cannot both succeed
+var rotateScript = goredis.NewScript(`
+if redis.call('HGET', KEYS[1], 'refresh') == ARGV[1] then
+	redis.call('HSET', KEYS[1], 'refresh', ARGV[2], 'last_seen', ARGV[3], 'ip', ARGV[4])
+	return 1
+end
+return 0
+`)
+
+// touchScript records activity without resurrecting a session revoked meanwhile
+var touchScript = goredis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('HSET', KEYS[1], 'last_seen', ARGV[1], 'ip', ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// Session is a logged-in device. All refresh tokens issued to a session belong
+// to the same rotation family.
+type Session struct {
+	ID        string    `json:"id"`
+	UserID    uint      `json:"user_id"`
+	Device    string    `json:"device"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// SessionStore keeps sessions and refresh tokens in Redis.
+//
+// Keys:
+//
+//	session:<sid>               hash of the session fields and the current refresh token hash
+//	user_sessions:<uid>         set of the user's session IDs
+//	refresh_token:<sha256>      session ID, kept for every token issued so reuse can be detected
+type SessionStore struct {
+	redisClient *redis.Client
+	refreshTTL  time.Duration
+}
+
+// NewSessionStore creates a new session store
+func NewSessionStore(redisClient *redis.Client, refreshTTL time.Duration) *SessionStore {
+	return &SessionStore{
+		redisClient: redisClient,
+		refreshTTL:  refreshTTL,
+	}
+}
+
+// RefreshTTL returns how long a refresh token stays valid
+func (s *SessionStore) RefreshTTL() time.Duration {
+	return s.refreshTTL
+}
+
+// Create starts a new session and returns it with its first refresh token
+func (s *SessionStore) Create(ctx context.Context, userID uint, device, ip string) (*Session, string, error) {
+	sessionID, err := randomToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := &Session{
+		ID:        sessionID,
+		UserID:    userID,
+		Device:    device,
+		IP:        ip,
+		CreatedAt: now,
+		LastSeen:  now,
+	}
+
+	pipe := s.redisClient.TxPipeline()
+	pipe.HSet(ctx, sessionKey(sessionID), map[string]interface{}{
+		"user_id":    userID,
+		"device":     device,
+		"ip":         ip,
+		"created_at": now.Unix(),
+		"last_seen":  now.Unix(),
+		"refresh":    hashToken(refreshToken),
+	})
+	pipe.Expire(ctx, sessionKey(sessionID), s.refreshTTL)
+	pipe.SAdd(ctx, userSessionsKey(userID), sessionID)
+	pipe.Expire(ctx, userSessionsKey(userID), s.refreshTTL)
+	pipe.Set(ctx, refreshTokenKey(refreshToken), sessionID, s.refreshTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, "", fmt.Errorf("failed to store session: %w", err)
+	}
+
+	return session, refreshToken, nil
+}
+
+// Rotate exchanges a refresh token for a new one. Presenting a token that has
+// already been rotated revokes the session it belongs to.
+func (s *SessionStore) Rotate(ctx context.Context, refreshToken, ip string) (*Session, string, error) {
+	sessionID, err := s.redisClient.Get(ctx, refreshTokenKey(refreshToken)).Result()
+	if err == goredis.Nil {
+		return nil, "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	session, err := s.Get(ctx, sessionID)
+	if err == ErrSessionNotFound {
+		return nil, "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	newToken, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	swapped, err := rotateScript.Run(ctx, s.redisClient, []string{sessionKey(sessionID)},
+		hashToken(refreshToken), hashToken(newToken), now.Unix(), ip).Int()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if swapped == 0 {
+		// The token was valid once but has been rotated since: assume it was stolen
+		if err := s.Revoke(ctx, session.UserID, sessionID); err != nil {
+			return nil, "", err
+		}
+		return nil, "", ErrRefreshTokenReused
+	}
+
+	pipe := s.redisClient.TxPipeline()
+	pipe.Set(ctx, refreshTokenKey(newToken), sessionID, s.refreshTTL)
+	pipe.Expire(ctx, sessionKey(sessionID), s.refreshTTL)
+	pipe.Expire(ctx, userSessionsKey(session.UserID), s.refreshTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, "", fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	session.IP = ip
+	session.LastSeen = now
+	return session, newToken, nil
+}
+
+// Get returns a session by ID
+func (s *SessionStore) Get(ctx context.Context, sessionID string) (*Session, error) {
+	fields, err := s.redisClient.HGetAll(ctx, sessionKey(sessionID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, ErrSessionNotFound
+	}
+
+	userID, _ := strconv.ParseUint(fields["user_id"], 10, 64)
+	createdAt, _ := strconv.ParseInt(fields["created_at"], 10, 64)
+	lastSeen, _ := strconv.ParseInt(fields["last_seen"], 10, 64)
+
+	return &Session{
+		ID:        sessionID,
+		UserID:    uint(userID),
+		Device:    fields["device"],
+		IP:        fields["ip"],
+		CreatedAt: time.Unix(createdAt, 0),
+		LastSeen:  time.Unix(lastSeen, 0),
+	}, nil
+}
+
+// Touch records activity on a session and reports whether it is still active
+func (s *SessionStore) Touch(ctx context.Context, sessionID, ip string) (bool, error) {
+	active, err := touchScript.Run(ctx, s.redisClient, []string{sessionKey(sessionID)}, time.Now().Unix(), ip).Int()
+	return active == 1, err
+}
+
+// List returns the user's active sessions, most recently used first
+func (s *SessionStore) List(ctx context.Context, userID uint) ([]Session, error) {
+	sessionIDs, err := s.redisClient.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []Session{}
+	for _, sessionID := range sessionIDs {
+		session, err := s.Get(ctx, sessionID)
+		if err == ErrSessionNotFound {
+			// Expired on its own; drop the stale reference
+			s.redisClient.SRem(ctx, userSessionsKey(userID), sessionID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+
+	return sessions, nil
+}
+
+// Revoke ends one of the user's sessions
+func (s *SessionStore) Revoke(ctx context.Context, userID uint, sessionID string) error {
+	session, err := s.Get(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	pipe := s.redisClient.TxPipeline()
+	pipe.Del(ctx, sessionKey(sessionID))
+	pipe.SRem(ctx, userSessionsKey(userID), sessionID)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// RevokeOthers ends every session of the user except keepID and returns how many were ended.
+// Pass an empty keepID to end all of them.
+func (s *SessionStore) RevokeOthers(ctx context.Context, userID uint, keepID string) (int, error) {
+	sessionIDs, err := s.redisClient.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, sessionID := range sessionIDs {
+		if sessionID == keepID {
+			continue
+		}
+		err := s.Revoke(ctx, userID, sessionID)
+		if err == ErrSessionNotFound {
+			// Already expired, so nothing was revoked
+			continue
+		}
+		if err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+
+	return revoked, nil
+}
+
+// randomToken returns n random bytes encoded for use in URLs and headers
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken hashes a refresh token so Redis never holds usable tokens
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func sessionKey(sessionID string) string {
+	return "session:" + sessionID
+}
+
+func userSessionsKey(userID uint) string {
+	return "user_sessions:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+func refreshTokenKey(token string) string {
+	return "refresh_token:" + hashToken(token)
+}
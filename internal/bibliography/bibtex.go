@@ -0,0 +1,191 @@
+package bibliography
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"freescholar-backend/internal/models"
+	"freescholar-backend/pkg/bibtex"
+)
+
+// bibtexTypes maps BibTeX entry types onto publication types
+var bibtexTypes = map[string]string{
+	"article":       models.PublicationTypeArticle,
+	"inproceedings": models.PublicationTypeConference,
+	"conference":    models.PublicationTypeConference,
+	"proceedings":   models.PublicationTypeConference,
+	"book":          models.PublicationTypeBook,
+	"inbook":        models.PublicationTypeBook,
+	"incollection":  models.PublicationTypeBook,
+}
+
+// bibtexMonths maps month names to numbers; the month macros are expanded by the parser
+var bibtexMonths = map[string]int{
+	"january": 1, "february": 2, "march": 3, "april": 4, "may": 5, "june": 6,
+	"july": 7, "august": 8, "september": 9, "october": 10, "november": 11, "december": 12,
+}
+
+// FromBibTeX creates a record of a BibTeX entry
+func FromBibTeX(entry bibtex.Entry) (Record, error) {
+	record := Record{
+		Type:      bibtexTypes[entry.Type],
+		Key:       entry.Key,
+		Title:     entry.Field("title"),
+		Abstract:  entry.Field("abstract"),
+		Authors:   bibtex.SplitNames(entry.Field("author")),
+		DOI:       entry.Field("doi"),
+		Volume:    entry.Field("volume"),
+		Issue:     entry.Field("number"),
+		Pages:     entry.Field("pages"),
+		Publisher: entry.Field("publisher"),
+		URL:       entry.Field("url"),
+	}
+	if record.Type == "" {
+		record.Type = models.PublicationTypeOther
+	}
+	if record.Publisher == "" {
+		record.Publisher = entry.Field("organization")
+	}
+
+	switch record.Type {
+	case models.PublicationTypeConference:
+		record.Container = entry.Field("booktitle")
+	case models.PublicationTypeBook:
+		record.Container = entry.Field("series")
+		if entry.Type != "book" {
+			record.Container = entry.Field("booktitle")
+		}
+	default:
+		record.Container = entry.Field("journal")
+	}
+	if record.Container == "" {
+		record.Container = entry.Field("journaltitle")
+	}
+
+	for _, keyword := range strings.FieldsFunc(entry.Field("keywords"), func(r rune) bool { return r == ',' || r == ';' }) {
+		record.Keywords = append(record.Keywords, keyword)
+	}
+
+	if record.Title == "" {
+		return record, errors.New("missing title")
+	}
+
+	year := entry.Field("year")
+	if date := entry.Field("date"); year == "" && len(date) >= 4 {
+		// biblatex: date = {2020-06-01}
+		year = date[:4]
+		if parts := strings.Split(date, "-"); len(parts) > 1 {
+			record.Month, _ = strconv.Atoi(parts[1])
+			if len(parts) > 2 {
+				record.Day, _ = strconv.Atoi(parts[2])
+			}
+		}
+	}
+	if year == "" {
+		return record, errors.New("missing year")
+	}
+	var err error
+	if record.Year, err = strconv.Atoi(year); err != nil || record.Year < 1 || record.Year > 9999 {
+		return record, fmt.Errorf("invalid year %q", year)
+	}
+
+	if month := strings.ToLower(strings.TrimSpace(entry.Field("month"))); month != "" {
+		if record.Month, err = strconv.Atoi(month); err != nil {
+			record.Month = bibtexMonths[month]
+		}
+	}
+	if record.Month < 1 || record.Month > 12 {
+		record.Month = 0
+		record.Day = 0
+	}
+	if record.Day < 1 || record.Day > 31 {
+		record.Day = 0
+	}
+
+	record.normalize()
+	return record, nil
+}
+
+// ToBibTeX creates a BibTeX entry of a record. The record needs a Key.
+func ToBibTeX(record Record) bibtex.Entry {
+	entry := bibtex.Entry{
+		Type:   "misc",
+		Key:    record.Key,
+		Fields: make(map[string]string),
+	}
+
+	switch record.Type {
+	case models.PublicationTypeArticle:
+		entry.Type = "article"
+		entry.Fields["journal"] = record.Container
+	case models.PublicationTypeConference:
+		entry.Type = "inproceedings"
+		entry.Fields["booktitle"] = record.Container
+	case models.PublicationTypeBook:
+		entry.Type = "book"
+		entry.Fields["series"] = record.Container
+	default:
+		entry.Fields["howpublished"] = record.Container
+	}
+
+	authors := make([]string, len(record.Authors))
+	for i, author := range record.Authors {
+		authors[i] = bibtex.SortName(author)
+	}
+	entry.Fields["author"] = strings.Join(authors, " and ")
+
+	entry.Fields["title"] = record.Title
+	entry.Fields["abstract"] = record.Abstract
+	entry.Fields["keywords"] = strings.Join(record.Keywords, ", ")
+	entry.Fields["doi"] = record.DOI
+	entry.Fields["volume"] = record.Volume
+	entry.Fields["number"] = record.Issue
+	entry.Fields["pages"] = record.Pages
+	entry.Fields["publisher"] = record.Publisher
+	entry.Fields["url"] = record.URL
+	if record.Year > 0 {
+		entry.Fields["year"] = strconv.Itoa(record.Year)
+	}
+	if record.Month > 0 {
+		entry.Fields["month"] = strconv.Itoa(record.Month)
+	}
+
+	return entry
+}
+
+// ParseBibTeX reads records from a BibTeX document. Entries that fail to
+// parse or map are returned as failed results in document order.
+func ParseBibTeX(input string) ([]Record, []ImportResult) {
+	entries, parseErrs := bibtex.Parse(input)
+
+	var records []Record
+	var failed []ImportResult
+	for _, err := range parseErrs {
+		failed = append(failed, ImportResult{Line: err.Line, Key: err.Key, Status: StatusError, Error: err.Msg})
+	}
+	for _, entry := range entries {
+		record, err := FromBibTeX(entry)
+		if err != nil {
+			failed = append(failed, ImportResult{Line: entry.Line, Key: entry.Key, Status: StatusError, Error: err.Error()})
+			continue
+		}
+		record.line = entry.Line
+		records = append(records, record)
+	}
+
+	return records, failed
+}
+
+// WriteBibTeX writes records as BibTeX with generated citation keys
+func WriteBibTeX(w io.Writer, records []Record) error {
+	AssignKeys(records)
+
+	entries := make([]bibtex.Entry, len(records))
+	for i, record := range records {
+		entries[i] = ToBibTeX(record)
+	}
+	return bibtex.Write(w, entries)
+}
@@ -0,0 +1,254 @@
+package bibliography
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"freescholar-backend/internal/models"
+	"freescholar-backend/pkg/bibtex"
+)
+
+// cslTypes maps CSL item types onto publication types
+var cslTypes = map[string]string{
+	"article":          models.PublicationTypeArticle,
+	"article-journal":  models.PublicationTypeArticle,
+	"article-magazine": models.PublicationTypeArticle,
+	"paper-conference": models.PublicationTypeConference,
+	"book":             models.PublicationTypeBook,
+	"chapter":          models.PublicationTypeBook,
+}
+
+// cslTypeNames are the CSL item types written for each publication type
+var cslTypeNames = map[string]string{
+	models.PublicationTypeArticle:    "article-journal",
+	models.PublicationTypeConference: "paper-conference",
+	models.PublicationTypeBook:       "book",
+	models.PublicationTypeOther:      "document",
+}
+
+// cslItem is an item of CSL-JSON as written by Zotero and citeproc
+type cslItem struct {
+	ID             interface{} `json:"id"`
+	Type           string      `json:"type"`
+	Title          string      `json:"title,omitempty"`
+	Abstract       string      `json:"abstract,omitempty"`
+	Author         []cslName   `json:"author,omitempty"`
+	Keyword        string      `json:"keyword,omitempty"`
+	DOI            string      `json:"DOI,omitempty"`
+	Issued         *cslDate    `json:"issued,omitempty"`
+	ContainerTitle string      `json:"container-title,omitempty"`
+	Volume         cslString   `json:"volume,omitempty"`
+	Issue          cslString   `json:"issue,omitempty"`
+	Page           cslString   `json:"page,omitempty"`
+	Publisher      string      `json:"publisher,omitempty"`
+	URL            string      `json:"URL,omitempty"`
+}
+
+// cslName is a personal name or, for institutions, a literal
+type cslName struct {
+	Family  string `json:"family,omitempty"`
+	Given   string `json:"given,omitempty"`
+	Literal string `json:"literal,omitempty"`
+}
+
+// cslDate holds date-parts like [[2020, 6, 1]]; some tools only send raw
+type cslDate struct {
+	DateParts [][]cslString `json:"date-parts,omitempty"`
+	Raw       string        `json:"raw,omitempty"`
+}
+
+// cslString accepts JSON strings and numbers, which CSL allows for numeric variables
+type cslString string
+
+func (s *cslString) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		*s = cslString(value)
+		return nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+	*s = cslString(number.String())
+	return nil
+}
+
+// MarshalJSON writes plain integers as numbers
+func (s cslString) MarshalJSON() ([]byte, error) {
+	if n, err := strconv.Atoi(string(s)); err == nil && n > 0 && strconv.Itoa(n) == string(s) {
+		return []byte(string(s)), nil
+	}
+	return json.Marshal(string(s))
+}
+
+// ParseCSL reads records from a CSL-JSON array or a single CSL-JSON item
+func ParseCSL(input string) ([]Record, []ImportResult) {
+	input = strings.TrimSpace(input)
+
+	var raw []json.RawMessage
+	if strings.HasPrefix(input, "{") {
+		raw = []json.RawMessage{json.RawMessage(input)}
+	} else if err := json.Unmarshal([]byte(input), &raw); err != nil {
+		return nil, []ImportResult{{Status: StatusError, Error: "Invalid CSL-JSON: " + err.Error()}}
+	}
+
+	var records []Record
+	var failed []ImportResult
+	for i, message := range raw {
+		var item cslItem
+		if err := json.Unmarshal(message, &item); err != nil {
+			failed = append(failed, ImportResult{Line: i + 1, Status: StatusError, Error: "Invalid item: " + err.Error()})
+			continue
+		}
+
+		record, err := fromCSL(item)
+		if err != nil {
+			failed = append(failed, ImportResult{Line: i + 1, Key: record.Key, Title: record.Title, Status: StatusError, Error: err.Error()})
+			continue
+		}
+		// CSL-JSON has no lines; the item position orders the results
+		record.line = i + 1
+		records = append(records, record)
+	}
+
+	return records, failed
+}
+
+func fromCSL(item cslItem) (Record, error) {
+	record := Record{
+		Type:      cslTypes[item.Type],
+		Title:     strings.TrimSpace(item.Title),
+		Abstract:  item.Abstract,
+		DOI:       item.DOI,
+		Container: item.ContainerTitle,
+		Volume:    string(item.Volume),
+		Issue:     string(item.Issue),
+		Pages:     string(item.Page),
+		Publisher: item.Publisher,
+		URL:       item.URL,
+	}
+	if item.ID != nil {
+		record.Key = fmt.Sprint(item.ID)
+	}
+	if record.Type == "" {
+		record.Type = models.PublicationTypeOther
+	}
+
+	for _, name := range item.Author {
+		if name.Literal != "" {
+			record.Authors = append(record.Authors, name.Literal)
+			continue
+		}
+		record.Authors = append(record.Authors, strings.TrimSpace(name.Given+" "+name.Family))
+	}
+
+	if item.Keyword != "" {
+		record.Keywords = strings.FieldsFunc(item.Keyword, func(r rune) bool { return r == ',' || r == ';' })
+	}
+
+	if record.Title == "" {
+		return record, errors.New("missing title")
+	}
+
+	var parts []string
+	if item.Issued != nil {
+		if len(item.Issued.DateParts) > 0 {
+			for _, part := range item.Issued.DateParts[0] {
+				parts = append(parts, string(part))
+			}
+		} else if item.Issued.Raw != "" {
+			parts = strings.Split(item.Issued.Raw, "-")
+		}
+	}
+	if len(parts) == 0 || parts[0] == "" {
+		return record, errors.New("missing issued date")
+	}
+
+	var err error
+	if record.Year, err = strconv.Atoi(parts[0]); err != nil || record.Year < 1 || record.Year > 9999 {
+		return record, fmt.Errorf("invalid year %q", parts[0])
+	}
+	if len(parts) > 1 {
+		if month, err := strconv.Atoi(parts[1]); err == nil && month >= 1 && month <= 12 {
+			record.Month = month
+			if len(parts) > 2 {
+				if day, err := strconv.Atoi(parts[2]); err == nil && day >= 1 && day <= 31 {
+					record.Day = day
+				}
+			}
+		}
+	}
+
+	record.normalize()
+	return record, nil
+}
+
+// toCSL creates a CSL-JSON item of a record
+func toCSL(record Record) cslItem {
+	item := cslItem{
+		ID:             record.Key,
+		Type:           cslTypeNames[record.Type],
+		Title:          record.Title,
+		Abstract:       record.Abstract,
+		Keyword:        strings.Join(record.Keywords, ", "),
+		DOI:            record.DOI,
+		ContainerTitle: record.Container,
+		Volume:         cslString(record.Volume),
+		Issue:          cslString(record.Issue),
+		Page:           cslString(record.Pages),
+		Publisher:      record.Publisher,
+		URL:            record.URL,
+	}
+	if item.Type == "" {
+		item.Type = "document"
+	}
+
+	for _, author := range record.Authors {
+		given, family := bibtex.SplitName(author)
+		if given == "" {
+			item.Author = append(item.Author, cslName{Literal: family})
+		} else {
+			item.Author = append(item.Author, cslName{Family: family, Given: given})
+		}
+	}
+
+	if record.Year > 0 {
+		parts := []cslString{cslString(strconv.Itoa(record.Year))}
+		if record.Month > 0 {
+			parts = append(parts, cslString(strconv.Itoa(record.Month)))
+			if record.Day > 0 {
+				parts = append(parts, cslString(strconv.Itoa(record.Day)))
+			}
+		}
+		item.Issued = &cslDate{DateParts: [][]cslString{parts}}
+	}
+
+	return item
+}
+
+// WriteCSL writes records as a CSL-JSON array with generated item IDs
+func WriteCSL(w io.Writer, records []Record) error {
+	AssignKeys(records)
+
+	items := make([]cslItem, len(records))
+	for i, record := range records {
+		items[i] = toCSL(record)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(items)
+}
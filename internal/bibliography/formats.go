@@ -0,0 +1,62 @@
+package bibliography
+
+import (
+	"io"
+	"mime"
+	"strings"
+)
+
+// Format is a reference format publications can be imported from and exported to
+type Format struct {
+	// Name is the value of the format query parameter
+	Name      string
+	MediaType string
+	Extension string
+	// Parse reads records from a document; entries that cannot be read
+	// are returned as failed results
+	Parse func(input string) ([]Record, []ImportResult)
+	// Write writes records, generating citation keys for those without one
+	Write func(w io.Writer, records []Record) error
+}
+
+// Formats are the supported reference formats
+var Formats = []Format{
+	{Name: "bibtex", MediaType: "application/x-bibtex", Extension: ".bib", Parse: ParseBibTeX, Write: WriteBibTeX},
+	{Name: "ris", MediaType: "application/x-research-info-systems", Extension: ".ris", Parse: ParseRIS, Write: WriteRIS},
+	{Name: "csl-json", MediaType: "application/vnd.citationstyles.csl+json", Extension: ".json", Parse: ParseCSL, Write: WriteCSL},
+}
+
+// mediaTypeAliases are other media types clients send for a format
+var mediaTypeAliases = map[string]string{
+	"text/x-bibtex":             "bibtex",
+	"application/x-ris":         "ris",
+	"application/citeproc+json": "csl-json",
+}
+
+// LookupFormat returns the format with the given name
+func LookupFormat(name string) (Format, bool) {
+	for _, format := range Formats {
+		if format.Name == strings.ToLower(name) {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// NegotiateFormat returns the first format named in an Accept header.
+// Wildcards never select a format, so browsers and API clients that accept
+// anything keep getting JSON.
+func NegotiateFormat(accept string) (Format, bool) {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		for _, format := range Formats {
+			if mediaType == format.MediaType || mediaTypeAliases[mediaType] == format.Name {
+				return format, true
+			}
+		}
+	}
+	return Format{}, false
+}
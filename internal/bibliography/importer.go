@@ -0,0 +1,166 @@
+package bibliography
+
+import (
+	"errors"
+	"sort"
+
+	"freescholar-backend/internal/indexer"
+	"freescholar-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Statuses of an import result
+const (
+	StatusCreated   = "created"
+	StatusDuplicate = "duplicate"
+	StatusError     = "error"
+)
+
+// ImportResult reports the outcome of importing one entry
+type ImportResult struct {
+	// Index is the position of the entry in the document
+	Index int `json:"index"`
+	// Line is where the entry starts, for formats that have lines
+	Line   int    `json:"line,omitempty"`
+	Key    string `json:"key,omitempty"`
+	Title  string `json:"title,omitempty"`
+	ID     uint   `json:"id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ErrDuplicate is returned for a record whose DOI already exists
+var ErrDuplicate = errors.New("a publication with this DOI already exists")
+
+// Import creates a publication owned by ownerID for each record, each in its
+// own transaction so one bad entry does not undo the others. Authors are
+// matched by name or created, keywords likewise, and records whose DOI
+// already exists are reported as duplicates. The results of failed, which
+// could not be parsed, are merged in so results follow document order.
+func Import(db *gorm.DB, ownerID uint, records []Record, failed []ImportResult) []ImportResult {
+	results := append([]ImportResult{}, failed...)
+
+	for _, record := range records {
+		result := ImportResult{Line: record.line, Key: record.Key, Title: record.Title}
+
+		id, err := ImportRecord(db, ownerID, record)
+		switch {
+		case errors.Is(err, ErrDuplicate):
+			result.ID = id
+			result.Status = StatusDuplicate
+			result.Error = err.Error()
+		case err != nil:
+			result.Status = StatusError
+			result.Error = "Failed to create publication"
+		default:
+			result.ID = id
+			result.Status = StatusCreated
+		}
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Line < results[j].Line
+	})
+	for i := range results {
+		results[i].Index = i
+	}
+
+	return results
+}
+
+// ImportRecord creates the publication of a record in a transaction and
+// returns its ID, or the ID of the existing publication with ErrDuplicate
+func ImportRecord(db *gorm.DB, ownerID uint, record Record) (uint, error) {
+	record.normalize()
+
+	var id uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if record.DOI != "" {
+			// Trashed publications keep their DOI until they are purged
+			var existing models.Publication
+			result := tx.Unscoped().Where("doi = ?", record.DOI).Limit(1).Find(&existing)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				id = existing.ID
+				return ErrDuplicate
+			}
+		}
+
+		publication := record.Publication()
+		publication.OwnerID = ownerID
+		if err := tx.Create(&publication).Error; err != nil {
+			return err
+		}
+		id = publication.ID
+
+		for i, name := range record.Authors {
+			author, created, err := FindOrCreateAuthor(tx, name)
+			if err != nil {
+				return err
+			}
+			if created {
+				if err := indexer.IndexAuthor(tx, author.ID); err != nil {
+					return err
+				}
+			}
+
+			pubAuthor := models.PublicationAuthor{
+				PublicationID: publication.ID,
+				AuthorID:      author.ID,
+				Order:         i,
+			}
+			if err := tx.Create(&pubAuthor).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, name := range record.Keywords {
+			var keyword models.Keyword
+			if err := tx.Where(models.Keyword{Name: name}).FirstOrCreate(&keyword).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&publication).Association("Keywords").Append(&keyword); err != nil {
+				return err
+			}
+		}
+
+		// Link references that were waiting for this DOI
+		linked, err := models.ResolveCitations(tx, publication)
+		if err != nil {
+			return err
+		}
+		if linked > 0 {
+			if err := models.RecountCitations(tx, publication.ID); err != nil {
+				return err
+			}
+		}
+
+		return indexer.IndexPublications(tx, publication.ID)
+	})
+
+	return id, err
+}
+
+// FindOrCreateAuthor returns the author with the given name, creating it if
+// there is none, and reports whether it was created. Names compare case-insensitively under the MySQL collation;
+// of several namesakes the oldest is used.
+func FindOrCreateAuthor(tx *gorm.DB, name string) (models.Author, bool, error) {
+	var author models.Author
+	result := tx.Where("name = ?", name).Order("id ASC").Limit(1).Find(&author)
+	if result.Error != nil {
+		return author, false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return author, false, nil
+	}
+
+	author = models.Author{Name: name}
+	if err := tx.Create(&author).Error; err != nil {
+		return author, false, err
+	}
+	return author, true, nil
+}
@@ -0,0 +1,111 @@
+package bibliography
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"freescholar-backend/pkg/bibtex"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// keyStopwords are title words skipped when picking the key word
+var keyStopwords = map[string]bool{
+	"a": true, "an": true, "the": true, "on": true, "of": true, "in": true,
+	"for": true, "and": true, "to": true, "with": true, "from": true, "by": true,
+	"at": true, "is": true, "are": true, "via": true, "toward": true, "towards": true,
+}
+
+// CitationKey builds the key of a record: the first author's last name, the
+// year and the first significant title word, followed by the publication ID,
+// e.g. "smith2020deep_42". Keys only depend on the record, so a publication
+// has the same key in every export, and the ID keeps them unique. Names or
+// titles without Latin letters, such as Chinese ones, give "pub42".
+func CitationKey(record Record) string {
+	base := baseKey(record)
+	switch {
+	case record.ID == 0 && base == "":
+		return "pub"
+	case record.ID == 0:
+		return base
+	case base == "":
+		return "pub" + strconv.Itoa(int(record.ID))
+	default:
+		return base + "_" + strconv.Itoa(int(record.ID))
+	}
+}
+
+// baseKey returns the readable part of a citation key, or "" if the record
+// has no author name or title word that folds to ASCII
+func baseKey(record Record) string {
+	if len(record.Authors) == 0 {
+		return ""
+	}
+	name := fold(bibtex.LastName(record.Authors[0]))
+
+	var word string
+	for _, w := range strings.Fields(record.Title) {
+		w = fold(w)
+		if w != "" && !keyStopwords[w] {
+			word = w
+			break
+		}
+	}
+
+	if name == "" || word == "" {
+		return ""
+	}
+	if record.Year > 0 {
+		return name + strconv.Itoa(record.Year) + word
+	}
+	return name + word
+}
+
+// AssignKeys sets the citation keys of records that have none. Records that
+// are not stored publications have no ID to tell them apart, so those
+// sharing a key get the suffixes a, b, c... in input order.
+func AssignKeys(records []Record) {
+	groups := make(map[string][]int)
+	for i := range records {
+		if records[i].Key != "" {
+			continue
+		}
+		key := CitationKey(records[i])
+		if records[i].ID != 0 {
+			records[i].Key = key
+			continue
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	for key, indexes := range groups {
+		if len(indexes) == 1 {
+			records[indexes[0]].Key = key
+			continue
+		}
+		for n, i := range indexes {
+			records[i].Key = key + suffix(n)
+		}
+	}
+}
+
+// suffix returns a, b, ..., z, aa, ab, ...
+func suffix(n int) string {
+	s := string(rune('a' + n%26))
+	if n >= 26 {
+		s = suffix(n/26-1) + s
+	}
+	return s
+}
+
+// fold lowercases s and keeps only ASCII letters and digits, removing accents
+func fold(s string) string {
+	var out strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(s)) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
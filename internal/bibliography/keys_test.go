@@ -0,0 +1,59 @@
+package bibliography
+
+import "testing"
+
+func TestCitationKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		record Record
+		want   string
+	}{
+		{"author, year and title", Record{ID: 42, Authors: []string{"Smith, John"}, Year: 2020, Title: "The Deep Learning"}, "smith2020deep_42"},
+		{"accents", Record{ID: 7, Authors: []string{"José Müller"}, Year: 2019, Title: "Über Graphen"}, "muller2019uber_7"},
+		{"no year", Record{ID: 3, Authors: []string{"Smith, J."}, Title: "Graphs"}, "smithgraphs_3"},
+		{"chinese", Record{ID: 8, Authors: []string{"张伟"}, Year: 2020, Title: "深度学习"}, "pub8"},
+		{"chinese author", Record{ID: 9, Authors: []string{"张伟"}, Year: 2020, Title: "Deep learning"}, "pub9"},
+		{"no authors", Record{ID: 10, Year: 2020, Title: "Deep learning"}, "pub10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CitationKey(tt.record); got != tt.want {
+				t.Errorf("CitationKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssignKeysIsStable(t *testing.T) {
+	paper := Record{ID: 5, Authors: []string{"Smith, John"}, Year: 2020, Title: "Deep nets"}
+	twin := Record{ID: 9, Authors: []string{"Smith, Jane"}, Year: 2020, Title: "Deep graphs"}
+
+	alone := []Record{paper}
+	AssignKeys(alone)
+	together := []Record{twin, paper}
+	AssignKeys(together)
+
+	if alone[0].Key != together[1].Key {
+		t.Errorf("key depends on the export: %q alone, %q with others", alone[0].Key, together[1].Key)
+	}
+	if together[0].Key == together[1].Key {
+		t.Errorf("records share the key %q", together[0].Key)
+	}
+}
+
+func TestAssignKeysWithoutIDs(t *testing.T) {
+	records := []Record{
+		{Authors: []string{"Smith"}, Year: 2020, Title: "Deep nets"},
+		{Authors: []string{"Smith"}, Year: 2020, Title: "Deep graphs"},
+		{Key: "kept"},
+	}
+	AssignKeys(records)
+
+	want := []string{"smith2020deepa", "smith2020deepb", "kept"}
+	for i, record := range records {
+		if record.Key != want[i] {
+			t.Errorf("records[%d].Key = %q, want %q", i, record.Key, want[i])
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Package bibliography converts publications to and from reference formats
+// such as BibTeX. Each format maps onto Record, so conversions between
+// formats and the database share one set of rules.
+package bibliography
+
+import (
+	"strings"
+	"time"
+
+	"freescholar-backend/internal/indexer"
+	"freescholar-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Record is a publication in format-neutral form
+type Record struct {
+	// ID is the publication ID of exported records
+	ID uint
+	// Type is one of the models.PublicationType constants
+	Type string
+	// Key is the citation key of BibTeX or the ID of CSL-JSON
+	Key      string
+	Title    string
+	Abstract string
+	// Authors are "First Last" names in byline order
+	Authors  []string
+	Keywords []string
+	DOI      string
+	// Year is required; Month and Day are 0 when unknown
+	Year  int
+	Month int
+	Day   int
+	// Container is the journal, proceedings or book series
+	Container string
+	Volume    string
+	Issue     string
+	Pages     string
+	Publisher string
+	URL       string
+
+	// line is where an imported record starts in its document
+	line int
+}
+
+// FromPublication creates a record of a publication and its authors and keywords
+func FromPublication(publication models.Publication, authors, keywords []string) Record {
+	record := Record{
+		ID:        publication.ID,
+		Type:      publication.Type,
+		Title:     publication.Title,
+		Abstract:  publication.Abstract,
+		Authors:   authors,
+		Keywords:  keywords,
+		DOI:       string(publication.DOI),
+		Container: publication.Journal,
+		Volume:    publication.Volume,
+		Issue:     publication.Issue,
+		Pages:     publication.Pages,
+		Publisher: publication.Publisher,
+		URL:       publication.URL,
+	}
+
+	if date := publication.PublicationDate; !date.IsZero() {
+		record.Year = date.Year()
+		// Dates imported with only a year are stored as January 1st
+		if date.Month() != time.January || date.Day() != 1 {
+			record.Month = int(date.Month())
+			record.Day = date.Day()
+		}
+	}
+
+	return record
+}
+
+// Load creates records of the given publications in the order of ids,
+// skipping IDs that do not exist
+func Load(db *gorm.DB, ids []uint) ([]Record, error) {
+	var publications []models.Publication
+	if err := db.Where("id IN ?", ids).Find(&publications).Error; err != nil {
+		return nil, err
+	}
+
+	docs, err := indexer.LoadPublicationDocuments(db, publications)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]Record, len(publications))
+	for i, publication := range publications {
+		byID[publication.ID] = FromPublication(publication, docs[i].Authors, docs[i].Keywords)
+	}
+
+	records := make([]Record, 0, len(byID))
+	for _, id := range ids {
+		if record, ok := byID[id]; ok {
+			records = append(records, record)
+			delete(byID, id)
+		}
+	}
+	return records, nil
+}
+
+// Publication creates the publication of a record, without authors and keywords
+func (r Record) Publication() models.Publication {
+	month, day := r.Month, r.Day
+	if month == 0 {
+		month = 1
+	}
+	if day == 0 {
+		day = 1
+	}
+
+	return models.Publication{
+		Type:            r.Type,
+		Title:           r.Title,
+		Abstract:        r.Abstract,
+		DOI:             models.DOI(r.DOI),
+		PublicationDate: time.Date(r.Year, time.Month(month), day, 0, 0, 0, 0, time.UTC),
+		Journal:         r.Container,
+		Volume:          r.Volume,
+		Issue:           r.Issue,
+		Pages:           r.Pages,
+		Publisher:       r.Publisher,
+		URL:             r.URL,
+	}
+}
+
+// normalize trims fields and drops empty names and keywords
+func (r *Record) normalize() {
+	r.Title = strings.TrimSpace(r.Title)
+	r.DOI = models.ExtractDOI(r.DOI)
+	r.Authors = compact(r.Authors)
+	r.Keywords = compact(r.Keywords)
+	if r.Type == "" {
+		r.Type = models.PublicationTypeArticle
+	}
+}
+
+// compact trims values and drops empty ones and duplicates
+func compact(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[strings.ToLower(value)] {
+			continue
+		}
+		seen[strings.ToLower(value)] = true
+		out = append(out, value)
+	}
+	return out
+}
@@ -0,0 +1,264 @@
+package bibliography
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"freescholar-backend/internal/models"
+	"freescholar-backend/pkg/bibtex"
+)
+
+// risTypes maps RIS reference types onto publication types
+var risTypes = map[string]string{
+	"JOUR":   models.PublicationTypeArticle,
+	"JFULL":  models.PublicationTypeArticle,
+	"EJOUR":  models.PublicationTypeArticle,
+	"MGZN":   models.PublicationTypeArticle,
+	"CONF":   models.PublicationTypeConference,
+	"CPAPER": models.PublicationTypeConference,
+	"BOOK":   models.PublicationTypeBook,
+	"EBOOK":  models.PublicationTypeBook,
+	"CHAP":   models.PublicationTypeBook,
+	"ECHAP":  models.PublicationTypeBook,
+	"EDBOOK": models.PublicationTypeBook,
+}
+
+// risTypeNames are the RIS reference types written for each publication type
+var risTypeNames = map[string]string{
+	models.PublicationTypeArticle:    "JOUR",
+	models.PublicationTypeConference: "CPAPER",
+	models.PublicationTypeBook:       "BOOK",
+	models.PublicationTypeOther:      "GEN",
+}
+
+// risEntry is a RIS reference as a list of tag values
+type risEntry struct {
+	line   int
+	fields map[string][]string
+}
+
+func (e risEntry) first(tags ...string) string {
+	for _, tag := range tags {
+		if values := e.fields[tag]; len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+func (e risEntry) all(tags ...string) []string {
+	var values []string
+	for _, tag := range tags {
+		values = append(values, e.fields[tag]...)
+	}
+	return values
+}
+
+// ParseRIS reads records from a RIS document. References run from a TY
+// line to an ER line; lines not of the form "XX  - value" continue the
+// previous value.
+func ParseRIS(input string) ([]Record, []ImportResult) {
+	var records []Record
+	var failed []ImportResult
+
+	var entry *risEntry
+	var lastTag string
+	finish := func() {
+		if entry == nil {
+			return
+		}
+		record, err := fromRIS(*entry)
+		if err != nil {
+			failed = append(failed, ImportResult{Line: entry.line, Key: entry.first("ID"), Title: record.Title, Status: StatusError, Error: err.Error()})
+		} else {
+			records = append(records, record)
+		}
+		entry = nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(strings.TrimPrefix(scanner.Text(), "\ufeff"), " \r")
+
+		tag, value, ok := risLine(text)
+		if !ok {
+			// Continuation of a long value
+			if entry != nil && lastTag != "" && strings.TrimSpace(text) != "" {
+				values := entry.fields[lastTag]
+				values[len(values)-1] += " " + strings.TrimSpace(text)
+			}
+			continue
+		}
+
+		switch tag {
+		case "TY":
+			if entry != nil {
+				failed = append(failed, ImportResult{Line: entry.line, Key: entry.first("ID"), Status: StatusError, Error: "missing ER before the next TY"})
+			}
+			entry = &risEntry{line: line, fields: map[string][]string{"TY": {value}}}
+		case "ER":
+			finish()
+		default:
+			if entry == nil {
+				failed = append(failed, ImportResult{Line: line, Status: StatusError, Error: fmt.Sprintf("%s outside a reference; expected TY", tag)})
+				continue
+			}
+			entry.fields[tag] = append(entry.fields[tag], value)
+		}
+		lastTag = tag
+	}
+
+	if entry != nil {
+		failed = append(failed, ImportResult{Line: entry.line, Key: entry.first("ID"), Status: StatusError, Error: "unexpected end of input; missing ER"})
+	}
+	if err := scanner.Err(); err != nil {
+		failed = append(failed, ImportResult{Status: StatusError, Error: err.Error()})
+	}
+
+	return records, failed
+}
+
+// risLine splits a line of the form "XX  - value"
+func risLine(text string) (string, string, bool) {
+	if len(text) < 5 || text[2:5] != "  -" {
+		return "", "", false
+	}
+	tag := text[:2]
+	for _, r := range tag {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return "", "", false
+		}
+	}
+	return tag, strings.TrimSpace(text[5:]), true
+}
+
+func fromRIS(entry risEntry) (Record, error) {
+	record := Record{
+		Type:      risTypes[entry.first("TY")],
+		Key:       entry.first("ID"),
+		Title:     entry.first("TI", "T1", "CT"),
+		Abstract:  entry.first("AB", "N2"),
+		DOI:       entry.first("DO"),
+		Container: entry.first("T2", "JO", "JF", "JA", "BT", "J2", "T3"),
+		Volume:    entry.first("VL"),
+		Issue:     entry.first("IS", "CP"),
+		Publisher: entry.first("PB"),
+		URL:       entry.first("UR"),
+		Keywords:  entry.all("KW"),
+		line:      entry.line,
+	}
+	if record.Type == "" {
+		record.Type = models.PublicationTypeOther
+	}
+
+	for _, name := range entry.all("AU", "A1") {
+		record.Authors = append(record.Authors, bibtex.DisplayName(name))
+	}
+
+	record.Pages = entry.first("SP")
+	if _, end := splitPages(record.Pages); end == "" && record.Pages != "" {
+		if end = entry.first("EP"); end != "" {
+			record.Pages += "–" + end
+		}
+	}
+
+	if record.Title == "" {
+		return record, errors.New("missing title")
+	}
+
+	// PY and Y1 hold "YYYY" or "YYYY/MM/DD/other", DA holds "YYYY/MM/DD"
+	date := entry.first("DA", "PY", "Y1")
+	parts := strings.Split(date, "/")
+	if parts[0] == "" {
+		return record, errors.New("missing year")
+	}
+	var err error
+	if record.Year, err = strconv.Atoi(parts[0]); err != nil || record.Year < 1 || record.Year > 9999 {
+		return record, fmt.Errorf("invalid year %q", parts[0])
+	}
+	if len(parts) > 1 {
+		if month, err := strconv.Atoi(parts[1]); err == nil && month >= 1 && month <= 12 {
+			record.Month = month
+			if len(parts) > 2 {
+				if day, err := strconv.Atoi(parts[2]); err == nil && day >= 1 && day <= 31 {
+					record.Day = day
+				}
+			}
+		}
+	}
+
+	record.normalize()
+	return record, nil
+}
+
+// WriteRIS writes records as RIS with generated IDs
+func WriteRIS(w io.Writer, records []Record) error {
+	AssignKeys(records)
+
+	bw := bufio.NewWriter(w)
+	tag := func(name, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			// Values are single-line
+			bw.WriteString(name + "  - " + strings.Join(strings.Fields(value), " ") + "\r\n")
+		}
+	}
+
+	for _, record := range records {
+		typeName, ok := risTypeNames[record.Type]
+		if !ok {
+			typeName = "GEN"
+		}
+		tag("TY", typeName)
+		tag("ID", record.Key)
+		tag("TI", record.Title)
+		for _, author := range record.Authors {
+			tag("AU", bibtex.SortName(author))
+		}
+		if record.Type == models.PublicationTypeBook {
+			tag("T3", record.Container)
+		} else {
+			tag("T2", record.Container)
+		}
+		if record.Year > 0 {
+			tag("PY", strconv.Itoa(record.Year))
+			date := fmt.Sprintf("%04d", record.Year)
+			if record.Month > 0 {
+				date += fmt.Sprintf("/%02d", record.Month)
+				if record.Day > 0 {
+					date += fmt.Sprintf("/%02d", record.Day)
+				}
+			}
+			tag("DA", date)
+		}
+		tag("VL", record.Volume)
+		tag("IS", record.Issue)
+		start, end := splitPages(record.Pages)
+		tag("SP", start)
+		tag("EP", end)
+		tag("PB", record.Publisher)
+		tag("DO", record.DOI)
+		tag("UR", record.URL)
+		for _, keyword := range record.Keywords {
+			tag("KW", keyword)
+		}
+		tag("AB", record.Abstract)
+		bw.WriteString("ER  - \r\n\r\n")
+	}
+
+	return bw.Flush()
+}
+
+// splitPages splits a page range such as "12-34" or "12–34"
+func splitPages(pages string) (start, end string) {
+	for _, sep := range []string{"--", "–", "—", "-"} {
+		if i := strings.Index(pages, sep); i >= 0 {
+			return strings.TrimSpace(pages[:i]), strings.TrimSpace(pages[i+len(sep):])
+		}
+	}
+	return strings.TrimSpace(pages), ""
+}
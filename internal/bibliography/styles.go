@@ -0,0 +1,612 @@
+package bibliography
+
+import (
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"freescholar-backend/internal/models"
+	"freescholar-backend/pkg/bibtex"
+)
+
+// Style is a citation style
+type Style struct {
+	// ID is the value of the style query parameter
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// render builds the citation of a record
+	render func(r Record, c *citation)
+}
+
+// Styles are the supported citation styles
+var Styles = []Style{
+	{ID: "apa", Name: "APA (7th edition)", render: renderAPA},
+	{ID: "mla", Name: "MLA (9th edition)", render: renderMLA},
+	{ID: "chicago", Name: "Chicago (17th edition, bibliography)", render: renderChicago},
+	{ID: "gbt7714", Name: "GB/T 7714-2015", render: renderGBT7714},
+	{ID: "ieee", Name: "IEEE", render: renderIEEE},
+}
+
+// LookupStyle returns the style with the given ID
+func LookupStyle(id string) (Style, bool) {
+	for _, style := range Styles {
+		if style.ID == strings.ToLower(id) {
+			return style, true
+		}
+	}
+	return Style{}, false
+}
+
+// Citation is a formatted citation in plain text and HTML
+type Citation struct {
+	Style string `json:"style"`
+	Name  string `json:"name"`
+	Text  string `json:"text"`
+	HTML  string `json:"html"`
+}
+
+// Cite formats the citation of a record in a style
+func (s Style) Cite(record Record) Citation {
+	c := &citation{}
+	s.render(record, c)
+	return Citation{
+		Style: s.ID,
+		Name:  s.Name,
+		Text:  strings.TrimSpace(c.text.String()),
+		HTML:  strings.TrimSpace(c.html.String()),
+	}
+}
+
+// citation builds the text and HTML forms of a citation side by side
+type citation struct {
+	text strings.Builder
+	html strings.Builder
+}
+
+func (c *citation) plain(s string) {
+	c.text.WriteString(s)
+	c.html.WriteString(html.EscapeString(s))
+}
+
+func (c *citation) italic(s string) {
+	if s == "" {
+		return
+	}
+	c.text.WriteString(s)
+	c.html.WriteString("<i>" + html.EscapeString(s) + "</i>")
+}
+
+// italicTitle writes a title in italics, followed by a period outside the italics
+func (c *citation) italicTitle(title string) {
+	title = terminate(title)
+	if strings.HasSuffix(title, ".") {
+		c.italic(strings.TrimSuffix(title, "."))
+		c.plain(".")
+		return
+	}
+	c.italic(title)
+}
+
+func (c *citation) link(s, href string) {
+	c.text.WriteString(s)
+	c.html.WriteString(`<a href="` + html.EscapeString(href) + `">` + html.EscapeString(s) + "</a>")
+}
+
+// doi writes the DOI as a resolver URL, optionally after a label such as "doi: "
+func (c *citation) doi(record Record, label string) {
+	if record.DOI == "" {
+		return
+	}
+	if label != "" {
+		c.plain(" " + label)
+		c.link(record.DOI, "https://doi.org/"+record.DOI)
+		return
+	}
+	c.plain(" ")
+	c.link("https://doi.org/"+record.DOI, "https://doi.org/"+record.DOI)
+}
+
+// terminate ends a sentence unless it already ends with punctuation
+func terminate(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.ContainsRune(".?!", rune(s[len(s)-1])) {
+		return s
+	}
+	return s + "."
+}
+
+// initials abbreviates given names, "Jean-Paul Marie" becoming "J.-P. M."
+func initials(given string, dots bool) string {
+	var words []string
+	for _, word := range strings.Fields(given) {
+		var parts []string
+		for _, part := range strings.Split(word, "-") {
+			r := []rune(part)
+			if len(r) == 0 {
+				continue
+			}
+			initial := string(unicode.ToUpper(r[0]))
+			if dots {
+				initial += "."
+			}
+			parts = append(parts, initial)
+		}
+		words = append(words, strings.Join(parts, "-"))
+	}
+	if dots {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(words, "")
+}
+
+// pageRange writes a page range with the given separator
+func pageRange(pages, sep string) string {
+	start, end := splitPages(pages)
+	if end == "" {
+		return start
+	}
+	return start + sep + end
+}
+
+func isRange(pages string) bool {
+	_, end := splitPages(pages)
+	return end != ""
+}
+
+// joinNames joins names as "a, b, c, and d" with the given final separator
+func joinNames(names []string, last string, serialComma bool) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + " " + last + " " + names[1]
+	}
+	head := strings.Join(names[:len(names)-1], ", ")
+	if serialComma {
+		return head + ", " + last + " " + names[len(names)-1]
+	}
+	return head + " " + last + " " + names[len(names)-1]
+}
+
+var monthAbbreviations = []string{"Jan.", "Feb.", "Mar.", "Apr.", "May", "Jun.", "Jul.", "Aug.", "Sep.", "Oct.", "Nov.", "Dec."}
+
+// renderAPA: Smith, J., & Doe, A. (2020). Title. Journal, 12(3), 45–67. https://doi.org/...
+func renderAPA(r Record, c *citation) {
+	var names []string
+	for _, author := range r.Authors {
+		given, family := bibtex.SplitName(author)
+		if given == "" {
+			names = append(names, family)
+		} else {
+			names = append(names, family+", "+initials(given, true))
+		}
+	}
+
+	var byline string
+	switch {
+	case len(names) > 20:
+		byline = strings.Join(names[:19], ", ") + ", . . . " + names[len(names)-1]
+	case len(names) > 1:
+		byline = strings.Join(names[:len(names)-1], ", ") + ", & " + names[len(names)-1]
+	case len(names) == 1:
+		byline = names[0]
+	}
+
+	year := "n.d."
+	if r.Year > 0 {
+		year = strconv.Itoa(r.Year)
+	}
+
+	if byline != "" {
+		c.plain(terminate(byline) + " (" + year + "). ")
+	}
+
+	switch r.Type {
+	case models.PublicationTypeBook:
+		c.italicTitle(r.Title)
+		if byline == "" {
+			c.plain(" (" + year + ").")
+		}
+		if r.Publisher != "" {
+			c.plain(" " + terminate(r.Publisher))
+		}
+	case models.PublicationTypeConference:
+		c.plain(terminate(r.Title))
+		if byline == "" {
+			c.plain(" (" + year + ").")
+		}
+		if r.Container != "" {
+			c.plain(" In ")
+			c.italic(r.Container)
+			if r.Pages != "" {
+				prefix := "p. "
+				if isRange(r.Pages) {
+					prefix = "pp. "
+				}
+				c.plain(" (" + prefix + pageRange(r.Pages, "–") + ")")
+			}
+			c.plain(".")
+		}
+		if r.Publisher != "" {
+			c.plain(" " + terminate(r.Publisher))
+		}
+	default:
+		c.plain(terminate(r.Title))
+		if byline == "" {
+			c.plain(" (" + year + ").")
+		}
+		if r.Container != "" {
+			c.plain(" ")
+			c.italic(r.Container)
+			if r.Volume != "" {
+				c.plain(", ")
+				c.italic(r.Volume)
+			}
+			if r.Issue != "" {
+				c.plain("(" + r.Issue + ")")
+			}
+			if r.Pages != "" {
+				c.plain(", " + pageRange(r.Pages, "–"))
+			}
+			c.plain(".")
+		}
+	}
+
+	c.doi(r, "")
+}
+
+// renderMLA: Smith, John, and Ann Doe. "Title." Journal, vol. 12, no. 3, 2020, pp. 45-67.
+func renderMLA(r Record, c *citation) {
+	var byline string
+	switch len(r.Authors) {
+	case 0:
+	case 1:
+		byline = bibtex.SortName(r.Authors[0])
+	case 2:
+		byline = bibtex.SortName(r.Authors[0]) + ", and " + r.Authors[1]
+	default:
+		byline = bibtex.SortName(r.Authors[0]) + ", et al"
+	}
+	if byline != "" {
+		c.plain(terminate(byline) + " ")
+	}
+
+	var details []string
+	if r.Type == models.PublicationTypeBook {
+		c.italicTitle(r.Title)
+		if r.Publisher != "" {
+			details = append(details, r.Publisher)
+		}
+		if r.Year > 0 {
+			details = append(details, strconv.Itoa(r.Year))
+		}
+		if len(details) > 0 {
+			c.plain(" " + strings.Join(details, ", ") + ".")
+		}
+		c.doi(r, "")
+		return
+	}
+
+	c.plain("“" + terminate(r.Title) + "”")
+	if r.Container != "" {
+		c.plain(" ")
+		c.italic(r.Container)
+	}
+	if r.Volume != "" {
+		details = append(details, "vol. "+r.Volume)
+	}
+	if r.Issue != "" {
+		details = append(details, "no. "+r.Issue)
+	}
+	if r.Year > 0 {
+		details = append(details, strconv.Itoa(r.Year))
+	}
+	if r.Pages != "" {
+		prefix := "p. "
+		if isRange(r.Pages) {
+			prefix = "pp. "
+		}
+		details = append(details, prefix+pageRange(r.Pages, "-"))
+	}
+	if len(details) > 0 {
+		if r.Container != "" {
+			c.plain(",")
+		}
+		c.plain(" " + strings.Join(details, ", "))
+	}
+	c.plain(".")
+	c.doi(r, "")
+}
+
+// renderChicago: Smith, John, and Ann Doe. "Title." Journal 12, no. 3 (2020): 45–67. https://doi.org/...
+func renderChicago(r Record, c *citation) {
+	names := r.Authors
+	etAl := false
+	if len(names) > 10 {
+		names = names[:7]
+		etAl = true
+	}
+
+	var byline string
+	if len(names) > 0 {
+		inverted := append([]string{bibtex.SortName(names[0])}, names[1:]...)
+		if etAl {
+			byline = strings.Join(inverted, ", ") + ", et al"
+		} else {
+			byline = joinNames(inverted, "and", true)
+			if len(inverted) == 2 {
+				byline = inverted[0] + ", and " + inverted[1]
+			}
+		}
+		c.plain(terminate(byline) + " ")
+	}
+
+	switch r.Type {
+	case models.PublicationTypeBook:
+		c.italicTitle(r.Title)
+		var details []string
+		if r.Publisher != "" {
+			details = append(details, r.Publisher)
+		}
+		if r.Year > 0 {
+			details = append(details, strconv.Itoa(r.Year))
+		}
+		if len(details) > 0 {
+			c.plain(" " + strings.Join(details, ", ") + ".")
+		}
+	case models.PublicationTypeConference:
+		c.plain("“" + terminate(r.Title) + "”")
+		if r.Container != "" {
+			c.plain(" In ")
+			c.italic(r.Container)
+			if r.Pages != "" {
+				c.plain(", " + pageRange(r.Pages, "–"))
+			}
+			c.plain(".")
+		}
+		var details []string
+		if r.Publisher != "" {
+			details = append(details, r.Publisher)
+		}
+		if r.Year > 0 {
+			details = append(details, strconv.Itoa(r.Year))
+		}
+		if len(details) > 0 {
+			c.plain(" " + strings.Join(details, ", ") + ".")
+		}
+	default:
+		c.plain("“" + terminate(r.Title) + "”")
+		if r.Container != "" {
+			c.plain(" ")
+			c.italic(r.Container)
+		}
+		if r.Volume != "" {
+			c.plain(" " + r.Volume)
+		}
+		if r.Issue != "" {
+			c.plain(", no. " + r.Issue)
+		}
+		if r.Year > 0 {
+			c.plain(" (" + strconv.Itoa(r.Year) + ")")
+		}
+		if r.Pages != "" {
+			c.plain(": " + pageRange(r.Pages, "–"))
+		}
+		c.plain(".")
+	}
+
+	c.doi(r, "")
+}
+
+// gbtTypes are the document type codes of GB/T 7714
+var gbtTypes = map[string]string{
+	models.PublicationTypeArticle:    "J",
+	models.PublicationTypeConference: "C",
+	models.PublicationTypeBook:       "M",
+}
+
+// renderGBT7714: SMITH J, DOE A, LEE B, et al. Title[J]. Journal, 2020, 12(3): 45-67. DOI:10....
+func renderGBT7714(r Record, c *citation) {
+	// Chinese entries use 等 for et al
+	etAl := "et al"
+	if isCJKText(r.Title) {
+		etAl = "等"
+	}
+
+	var names []string
+	for i, author := range r.Authors {
+		if i == 3 {
+			names = append(names, etAl)
+			break
+		}
+		given, family := bibtex.SplitName(author)
+		if given == "" {
+			names = append(names, family)
+			continue
+		}
+		names = append(names, strings.ToUpper(family)+" "+initials(given, false))
+	}
+	if len(names) > 0 {
+		c.plain(strings.Join(names, ", ") + ". ")
+	}
+
+	code, ok := gbtTypes[r.Type]
+	if !ok {
+		code = "Z"
+	}
+	c.plain(strings.TrimRight(r.Title, ".") + "[" + code + "]")
+
+	year := ""
+	if r.Year > 0 {
+		year = strconv.Itoa(r.Year)
+	}
+
+	switch r.Type {
+	case models.PublicationTypeBook:
+		c.plain(".")
+		var details []string
+		if r.Publisher != "" {
+			details = append(details, r.Publisher)
+		}
+		if year != "" {
+			details = append(details, year)
+		}
+		if len(details) > 0 {
+			c.plain(" " + strings.Join(details, ", "))
+		}
+		if r.Pages != "" {
+			c.plain(": " + pageRange(r.Pages, "-"))
+		}
+	case models.PublicationTypeConference:
+		if r.Container != "" {
+			c.plain("//" + r.Container)
+		}
+		c.plain(".")
+		var details []string
+		if r.Publisher != "" {
+			details = append(details, r.Publisher)
+		}
+		if year != "" {
+			details = append(details, year)
+		}
+		if len(details) > 0 {
+			c.plain(" " + strings.Join(details, ", "))
+		}
+		if r.Pages != "" {
+			c.plain(": " + pageRange(r.Pages, "-"))
+		}
+	default:
+		c.plain(".")
+		var details []string
+		if r.Container != "" {
+			details = append(details, r.Container)
+		}
+		if year != "" {
+			details = append(details, year)
+		}
+		if len(details) > 0 {
+			c.plain(" " + strings.Join(details, ", "))
+		}
+		if r.Volume != "" {
+			c.plain(", " + r.Volume)
+		}
+		if r.Issue != "" {
+			c.plain("(" + r.Issue + ")")
+		}
+		if r.Pages != "" {
+			c.plain(": " + pageRange(r.Pages, "-"))
+		}
+	}
+	c.plain(".")
+
+	if r.DOI != "" {
+		c.plain(" DOI:")
+		c.link(r.DOI, "https://doi.org/"+r.DOI)
+		c.plain(".")
+	}
+}
+
+// renderIEEE: J. Smith and A. Doe, "Title," Journal, vol. 12, no. 3, pp. 45–67, Jun. 2020, doi: 10....
+func renderIEEE(r Record, c *citation) {
+	var names []string
+	for _, author := range r.Authors {
+		given, family := bibtex.SplitName(author)
+		if given == "" {
+			names = append(names, family)
+		} else {
+			names = append(names, initials(given, true)+" "+family)
+		}
+	}
+
+	var byline string
+	if len(names) > 6 {
+		byline = names[0] + " et al."
+	} else {
+		byline = joinNames(names, "and", true)
+	}
+	if byline != "" {
+		c.plain(byline + ", ")
+	}
+
+	date := ""
+	if r.Year > 0 {
+		date = strconv.Itoa(r.Year)
+		if r.Month > 0 {
+			date = monthAbbreviations[r.Month-1] + " " + date
+		}
+	}
+
+	pages := ""
+	if r.Pages != "" {
+		pages = "p. " + pageRange(r.Pages, "–")
+		if isRange(r.Pages) {
+			pages = "p" + pages
+		}
+	}
+
+	if r.Type == models.PublicationTypeBook {
+		c.italic(strings.TrimRight(r.Title, "."))
+		c.plain(".")
+		var details []string
+		if r.Publisher != "" {
+			details = append(details, r.Publisher)
+		}
+		if date != "" {
+			details = append(details, date)
+		}
+		if len(details) > 0 {
+			c.plain(" " + strings.Join(details, ", "))
+		}
+	} else {
+		c.plain("“" + strings.TrimRight(r.Title, ".") + ",”")
+		var details []string
+		if r.Type == models.PublicationTypeConference {
+			if r.Container != "" {
+				c.plain(" in ")
+				c.italic(r.Container)
+			}
+			details = append(details, date, pages)
+		} else {
+			if r.Container != "" {
+				c.plain(" ")
+				c.italic(r.Container)
+			}
+			if r.Volume != "" {
+				details = append(details, "vol. "+r.Volume)
+			}
+			if r.Issue != "" {
+				details = append(details, "no. "+r.Issue)
+			}
+			details = append(details, pages, date)
+		}
+		comma := r.Container != ""
+		for _, detail := range details {
+			if detail == "" {
+				continue
+			}
+			if comma {
+				c.plain(",")
+			}
+			c.plain(" " + detail)
+			comma = true
+		}
+	}
+
+	if r.DOI != "" {
+		c.plain(", doi: ")
+		c.link(r.DOI, "https://doi.org/"+r.DOI)
+	}
+	c.plain(".")
+}
+
+func isCJKText(s string) bool {
+	for _, r := range s {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return true
+		}
+	}
+	return false
+}
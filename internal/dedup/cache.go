@@ -0,0 +1,97 @@
+package dedup
+
+import (
+	"sync"
+	"time"
+
+	"freescholar-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// maxCached bounds the number of thresholds kept by a Cache
+const maxCached = 8
+
+// Cache keeps the clusters found for each threshold and reuses them while the
+// publications are unchanged, so paging through duplicates does not rebuild
+// the signatures of every publication on each request
+type Cache struct {
+	db     *gorm.DB
+	maxAge time.Duration
+
+	mu      sync.Mutex
+	entries map[float64]cacheEntry
+}
+
+type cacheEntry struct {
+	clusters []Cluster
+	version  version
+	builtAt  time.Time
+}
+
+// version changes whenever a publication is added, edited, deleted or purged
+type version struct {
+	Count   int64
+	Updated *time.Time
+	Deleted *time.Time
+}
+
+// NewCache creates a cache of duplicate clusters. Clusters are rebuilt when
+// the publications change and at the latest after maxAge, which also picks up
+// changes to authors that leave the publications themselves untouched.
+func NewCache(db *gorm.DB, maxAge time.Duration) *Cache {
+	return &Cache{
+		db:      db,
+		maxAge:  maxAge,
+		entries: make(map[float64]cacheEntry),
+	}
+}
+
+// Find returns the clusters of Find, reusing the last result for the threshold if it is still current
+func (c *Cache) Find(threshold float64) ([]Cluster, error) {
+	current, err := currentVersion(c.db)
+	if err != nil {
+		return nil, err
+	}
+
+	// Builds are serialized so concurrent requests wait for one build rather than start their own
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[threshold]; ok && entry.version.equal(current) && time.Since(entry.builtAt) < c.maxAge {
+		return entry.clusters, nil
+	}
+
+	clusters, err := Find(c.db, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, entry := range c.entries {
+		if !entry.version.equal(current) || len(c.entries) >= maxCached {
+			delete(c.entries, key)
+		}
+	}
+	c.entries[threshold] = cacheEntry{clusters: clusters, version: current, builtAt: time.Now()}
+	return clusters, nil
+}
+
+// currentVersion reads the version of the publications table, deleted rows included
+func currentVersion(db *gorm.DB) (version, error) {
+	var v version
+	err := db.Unscoped().Model(&models.Publication{}).
+		Select("COUNT(*) AS count, MAX(updated_at) AS updated, MAX(deleted_at) AS deleted").
+		Scan(&v).Error
+	return v, err
+}
+
+func (v version) equal(other version) bool {
+	return v.Count == other.Count && sameTime(v.Updated, other.Updated) && sameTime(v.Deleted, other.Deleted)
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,380 @@
+// Package dedup finds publications that are likely the same work: rows
+// imported twice without a DOI, or with DOIs that only differ in form.
+//
+// Titles are compared by the Jaccard similarity of their character
+// trigrams. To avoid comparing every pair, each title gets a MinHash
+// signature whose bands are bucketed (locality-sensitive hashing); only
+// titles sharing a bucket are compared. Candidates must also agree on the
+// year, within one, and on the first author's family name when both have one.
+package dedup
+
+import (
+	"hash/fnv"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"freescholar-backend/internal/models"
+	"freescholar-backend/pkg/bibtex"
+
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+const (
+	// signatureSize is the number of MinHash functions, split into bands of bandRows
+	signatureSize = 64
+	bandRows      = 4
+	// maxBucket skips LSH buckets of very common titles such as "Editorial"
+	maxBucket = 200
+	// batchSize is how many publications are loaded per query
+	batchSize = 5000
+)
+
+// Reasons a pair of publications is considered a duplicate
+const (
+	ReasonDOI   = "doi"
+	ReasonTitle = "title"
+)
+
+// Cluster is a group of publications that are likely the same work
+type Cluster struct {
+	IDs []uint `json:"ids"`
+	// Similarity is the lowest title similarity of the pairs that formed the cluster
+	Similarity float64  `json:"similarity"`
+	Reasons    []string `json:"reasons"`
+}
+
+type entry struct {
+	id       uint
+	year     int
+	doi      string
+	author   string
+	trigrams map[uint32]bool
+}
+
+type edge struct {
+	similarity float64
+	reason     string
+}
+
+// coefficients of the MinHash functions, fixed so results are stable across runs
+var coefficients = func() [signatureSize][2]uint64 {
+	var c [signatureSize][2]uint64
+	state := uint64(0)
+	for i := range c {
+		for j := range c[i] {
+			// splitmix64
+			state += 0x9e3779b97f4a7c15
+			z := state
+			z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+			z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+			c[i][j] = z ^ (z >> 31)
+		}
+		c[i][0] |= 1
+	}
+	return c
+}()
+
+// Find returns clusters of likely duplicate publications whose titles have
+// at least the given similarity, most similar first
+func Find(db *gorm.DB, threshold float64) ([]Cluster, error) {
+	entries, err := load(db)
+	if err != nil {
+		return nil, err
+	}
+	return clusterEntries(entries, threshold), nil
+}
+
+// clusterEntries links likely duplicates among entries into clusters
+func clusterEntries(entries []entry, threshold float64) []Cluster {
+	parent := make([]int, len(entries))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	// members of each cluster by its root, to check new links against the whole cluster
+	members := make([][]int, len(entries))
+	for i := range members {
+		members[i] = []int{i}
+	}
+
+	edges := make(map[[2]int]edge)
+	link := func(a, b int, e edge) {
+		ra, rb := find(a), find(b)
+		edges[[2]int{a, b}] = e
+		if ra != rb {
+			parent[ra] = rb
+			members[rb] = append(members[rb], members[ra]...)
+			members[ra] = nil
+		}
+	}
+
+	// mergeable reports whether the clusters of a and b may be joined: a
+	// record linking two clusters whose records are incompatible, e.g. with
+	// different DOIs, must not join them, as they could never be merged
+	mergeable := func(a, b int) bool {
+		ra, rb := find(a), find(b)
+		if ra == rb {
+			return true
+		}
+		for _, x := range members[ra] {
+			for _, y := range members[rb] {
+				if !compatible(entries[x], entries[y]) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	// Equal DOIs in forms the unique index did not catch
+	byDOI := make(map[string][]int)
+	for i, e := range entries {
+		if e.doi != "" {
+			byDOI[e.doi] = append(byDOI[e.doi], i)
+		}
+	}
+	for _, group := range byDOI {
+		for _, i := range group[1:] {
+			link(group[0], i, edge{similarity: jaccard(entries[group[0]].trigrams, entries[i].trigrams), reason: ReasonDOI})
+		}
+	}
+
+	// Similar titles
+	buckets := make(map[uint64][]int)
+	for i, e := range entries {
+		if len(e.trigrams) == 0 {
+			continue
+		}
+		signature := minhash(e.trigrams)
+		for band := 0; band < signatureSize/bandRows; band++ {
+			h := fnv.New64a()
+			h.Write([]byte{byte(band)})
+			for _, value := range signature[band*bandRows : (band+1)*bandRows] {
+				h.Write([]byte{byte(value), byte(value >> 8), byte(value >> 16), byte(value >> 24)})
+			}
+			key := h.Sum64()
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	compared := make(map[[2]int]bool)
+	for _, bucket := range buckets {
+		if len(bucket) < 2 || len(bucket) > maxBucket {
+			continue
+		}
+		for x := 0; x < len(bucket); x++ {
+			for y := x + 1; y < len(bucket); y++ {
+				pair := [2]int{bucket[x], bucket[y]}
+				if compared[pair] {
+					continue
+				}
+				compared[pair] = true
+				if _, ok := edges[pair]; ok {
+					continue
+				}
+
+				a, b := entries[pair[0]], entries[pair[1]]
+				if !compatible(a, b) {
+					continue
+				}
+				if similarity := jaccard(a.trigrams, b.trigrams); similarity >= threshold && mergeable(pair[0], pair[1]) {
+					link(pair[0], pair[1], edge{similarity: similarity, reason: ReasonTitle})
+				}
+			}
+		}
+	}
+
+	// Collect the clusters
+	groups := make(map[int]*Cluster)
+	for pair, e := range edges {
+		root := find(pair[0])
+		cluster := groups[root]
+		if cluster == nil {
+			cluster = &Cluster{Similarity: 1}
+			groups[root] = cluster
+		}
+		cluster.Similarity = math.Min(cluster.Similarity, e.similarity)
+		if !slices.Contains(cluster.Reasons, e.reason) {
+			cluster.Reasons = append(cluster.Reasons, e.reason)
+		}
+	}
+	for i, e := range entries {
+		if cluster := groups[find(i)]; cluster != nil {
+			cluster.IDs = append(cluster.IDs, e.id)
+		}
+	}
+
+	clusters := make([]Cluster, 0, len(groups))
+	for _, cluster := range groups {
+		sort.Strings(cluster.Reasons)
+		cluster.Similarity = math.Round(cluster.Similarity*1000) / 1000
+		clusters = append(clusters, *cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Similarity != clusters[j].Similarity {
+			return clusters[i].Similarity > clusters[j].Similarity
+		}
+		return clusters[i].IDs[0] < clusters[j].IDs[0]
+	})
+
+	return clusters
+}
+
+// load reads the compared fields of all live publications in ID order
+func load(db *gorm.DB) ([]entry, error) {
+	var entries []entry
+	var lastID uint
+
+	for {
+		var publications []models.Publication
+		err := db.Select("id", "title", "doi", "publication_date").
+			Where("id > ?", lastID).
+			Order("id ASC").
+			Limit(batchSize).
+			Find(&publications).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(publications) == 0 {
+			return entries, nil
+		}
+
+		ids := make([]uint, len(publications))
+		for i, publication := range publications {
+			ids[i] = publication.ID
+		}
+
+		var authorRows []struct {
+			PublicationID uint
+			Name          string
+		}
+		err = db.Table("publication_authors").
+			Select("publication_authors.publication_id, authors.name").
+			Joins("JOIN authors ON authors.id = publication_authors.author_id AND authors.deleted_at IS NULL").
+			Where("publication_authors.publication_id IN ? AND publication_authors.deleted_at IS NULL", ids).
+			Order("publication_authors.publication_id, publication_authors.`order`").
+			Scan(&authorRows).Error
+		if err != nil {
+			return nil, err
+		}
+		firstAuthors := make(map[uint]string)
+		for _, row := range authorRows {
+			if _, ok := firstAuthors[row.PublicationID]; !ok {
+				firstAuthors[row.PublicationID] = fold(bibtex.LastName(row.Name))
+			}
+		}
+
+		for _, publication := range publications {
+			e := entry{
+				id:       publication.ID,
+				doi:      models.NormalizeDOI(string(publication.DOI)),
+				author:   firstAuthors[publication.ID],
+				trigrams: trigrams(NormalizeTitle(publication.Title)),
+			}
+			if !publication.PublicationDate.IsZero() {
+				e.year = publication.PublicationDate.Year()
+			}
+			entries = append(entries, e)
+		}
+
+		lastID = publications[len(publications)-1].ID
+	}
+}
+
+// compatible reports whether two publications may be the same work apart from their titles
+func compatible(a, b entry) bool {
+	if a.year != 0 && b.year != 0 && (a.year-b.year > 1 || b.year-a.year > 1) {
+		return false
+	}
+	if a.author != "" && b.author != "" && a.author != b.author {
+		return false
+	}
+	// Different DOIs are different works
+	return a.doi == "" || b.doi == "" || a.doi == b.doi
+}
+
+// NormalizeTitle lowercases a title, removes accents and punctuation and collapses whitespace
+func NormalizeTitle(title string) string {
+	var out strings.Builder
+	space := false
+	for _, r := range norm.NFD.String(strings.ToLower(title)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && out.Len() > 0 {
+				out.WriteRune(' ')
+			}
+			space = false
+			out.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return out.String()
+}
+
+// fold normalizes a family name for comparison
+func fold(name string) string {
+	return strings.ReplaceAll(NormalizeTitle(name), " ", "")
+}
+
+// trigrams returns the hashed character trigrams of a normalized title
+func trigrams(title string) map[uint32]bool {
+	if title == "" {
+		return nil
+	}
+	runes := []rune(" " + title + " ")
+	set := make(map[uint32]bool, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		h := fnv.New32a()
+		h.Write([]byte(string(runes[i : i+3])))
+		set[h.Sum32()] = true
+	}
+	return set
+}
+
+// minhash computes the MinHash signature of a trigram set
+func minhash(set map[uint32]bool) [signatureSize]uint32 {
+	var signature [signatureSize]uint32
+	for i := range signature {
+		signature[i] = math.MaxUint32
+	}
+	for value := range set {
+		for i, c := range coefficients {
+			h := uint32((c[0]*uint64(value) + c[1]) >> 32)
+			if h < signature[i] {
+				signature[i] = h
+			}
+		}
+	}
+	return signature
+}
+
+// jaccard returns the Jaccard similarity of two sets
+func jaccard(a, b map[uint32]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	shared := 0
+	for value := range a {
+		if b[value] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
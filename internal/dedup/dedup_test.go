@@ -0,0 +1,57 @@
+package dedup
+
+import (
+	"slices"
+	"testing"
+)
+
+func testEntry(id uint, title, doi string) entry {
+	return entry{id: id, year: 2020, doi: doi, author: "smith", trigrams: trigrams(NormalizeTitle(title))}
+}
+
+func TestClusterEntries(t *testing.T) {
+	entries := []entry{
+		testEntry(1, "Deep learning for graphs", ""),
+		testEntry(2, "Deep Learning for Graphs.", ""),
+		testEntry(3, "A survey of protein folding", "10.1000/a"),
+		testEntry(4, "Something else entirely", "10.1000/A"),
+	}
+	entries[3].doi = entries[2].doi
+
+	clusters := clusterEntries(entries, 0.85)
+	if len(clusters) != 2 {
+		t.Fatalf("got %d clusters, want 2: %+v", len(clusters), clusters)
+	}
+	for _, cluster := range clusters {
+		switch {
+		case slices.Equal(cluster.IDs, []uint{1, 2}):
+			if !slices.Equal(cluster.Reasons, []string{ReasonTitle}) {
+				t.Errorf("title cluster reasons = %v", cluster.Reasons)
+			}
+		case slices.Equal(cluster.IDs, []uint{3, 4}):
+			if !slices.Equal(cluster.Reasons, []string{ReasonDOI}) {
+				t.Errorf("DOI cluster reasons = %v", cluster.Reasons)
+			}
+		default:
+			t.Errorf("unexpected cluster %v", cluster.IDs)
+		}
+	}
+}
+
+func TestClusterEntriesKeepsDifferentDOIsApart(t *testing.T) {
+	// The record without a DOI matches both, but they are different works
+	entries := []entry{
+		testEntry(1, "Deep learning for graphs", "10.1000/one"),
+		testEntry(2, "Deep learning for graphs", ""),
+		testEntry(3, "Deep learning for graphs", "10.1000/two"),
+	}
+
+	for _, cluster := range clusterEntries(entries, 0.85) {
+		if slices.Contains(cluster.IDs, 1) && slices.Contains(cluster.IDs, 3) {
+			t.Errorf("cluster %v joins publications with different DOIs", cluster.IDs)
+		}
+		if len(cluster.IDs) != 2 {
+			t.Errorf("cluster %v should pair the record without a DOI with one of the others", cluster.IDs)
+		}
+	}
+}
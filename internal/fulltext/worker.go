@@ -0,0 +1,207 @@
+// Package fulltext extracts the text of uploaded PDFs in the background so
+// publications can be searched by their content.
+package fulltext
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"freescholar-backend/config"
+	"freescholar-backend/internal/indexer"
+	"freescholar-backend/internal/media"
+	"freescholar-backend/internal/models"
+	"freescholar-backend/pkg/pdftext"
+
+	"gorm.io/gorm"
+)
+
+// Worker extracts the text of files waiting for extraction
+type Worker struct {
+	db           *gorm.DB
+	store        *media.Store
+	batchSize    int
+	pollInterval time.Duration
+	maxChars     int
+}
+
+// NewWorker creates a new extraction worker
+func NewWorker(db *gorm.DB, store *media.Store, cfg config.FulltextConfig) *Worker {
+	w := &Worker{
+		db:           db,
+		store:        store,
+		batchSize:    cfg.BatchSize,
+		pollInterval: time.Duration(cfg.PollInterval) * time.Second,
+		maxChars:     cfg.MaxChars,
+	}
+
+	if w.batchSize < 1 {
+		w.batchSize = 10
+	}
+	if w.pollInterval <= 0 {
+		w.pollInterval = 10 * time.Second
+	}
+	if w.maxChars < 1 {
+		w.maxChars = 1000000
+	}
+
+	return w
+}
+
+// Run extracts pending files until the context is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.LiftEmbargoes(); err != nil {
+			log.Printf("Text extraction: %v", err)
+		}
+
+		// Keep going while full batches come back
+		for ctx.Err() == nil {
+			processed, err := w.ProcessBatch(ctx)
+			if err != nil {
+				log.Printf("Text extraction: %v", err)
+				break
+			}
+			if processed < w.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// LiftEmbargoes makes files whose embargo has ended public and schedules
+// their publications for re-indexing, as only the text of public files is searchable
+func (w *Worker) LiftEmbargoes() error {
+	return w.db.Transaction(func(tx *gorm.DB) error {
+		var files []models.File
+		err := tx.Select("id", "publication_id").
+			Where("visibility = ? AND embargo_until <= ?", models.VisibilityEmbargoed, time.Now()).
+			Find(&files).Error
+		if err != nil {
+			return fmt.Errorf("failed to load embargoed files: %w", err)
+		}
+		if len(files) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(files))
+		var publicationIDs []uint
+		for _, file := range files {
+			ids = append(ids, file.ID)
+			if file.PublicationID != nil {
+				publicationIDs = append(publicationIDs, *file.PublicationID)
+			}
+		}
+
+		err = tx.Model(&models.File{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"visibility":    models.VisibilityPublic,
+			"embargo_until": nil,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to lift embargoes: %w", err)
+		}
+		return indexer.IndexPublications(tx, publicationIDs...)
+	})
+}
+
+// ProcessBatch extracts the text of a batch of pending PDFs and schedules
+// their publications for re-indexing. It returns the number of files processed.
+func (w *Worker) ProcessBatch(ctx context.Context) (int, error) {
+	var files []models.File
+	err := w.db.Where("text_status = ? AND content_type = ?", models.TextPending, "application/pdf").
+		Order("id ASC").
+		Limit(w.batchSize).
+		Find(&files).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to load pending files: %w", err)
+	}
+
+	for _, file := range files {
+		status := models.TextDone
+		text, err := w.extract(ctx, file)
+		if err != nil {
+			log.Printf("Text extraction: file %d: %v", file.ID, err)
+			status = models.TextFailed
+		}
+
+		err = w.db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&file).
+				Where("text_status = ?", models.TextPending).
+				Updates(map[string]interface{}{"text": text, "text_status": status})
+			if result.Error != nil || result.RowsAffected == 0 || file.PublicationID == nil {
+				return result.Error
+			}
+			return indexer.IndexPublications(tx, *file.PublicationID)
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to save text of file %d: %w", file.ID, err)
+		}
+	}
+
+	return len(files), nil
+}
+
+// extract returns the text of a file, reusing the text of an identical file
+// extracted earlier
+func (w *Worker) extract(ctx context.Context, file models.File) (text string, err error) {
+	if file.Checksum != "" {
+		var done models.File
+		result := w.db.Select("id", "text").
+			Where("checksum = ? AND text_status = ? AND id <> ?", file.Checksum, models.TextDone, file.ID).
+			Limit(1).
+			Find(&done)
+		if result.Error != nil {
+			return "", result.Error
+		}
+		if result.RowsAffected > 0 {
+			return done.Text, nil
+		}
+	}
+
+	content, err := w.store.Open(ctx, file.FilePath)
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+
+	// Uploaded files are untrusted; a malformed one must not take the server down
+	defer func() {
+		if r := recover(); r != nil {
+			text, err = "", fmt.Errorf("extraction failed: %v", r)
+		}
+	}()
+
+	text, err = pdftext.Extract(data)
+	if err != nil {
+		return "", err
+	}
+	return truncate(text, w.maxChars), nil
+}
+
+// truncate cuts text to at most max characters
+func truncate(text string, max int) string {
+	count := 0
+	for i := range text {
+		if count == max {
+			return text[:i]
+		}
+		count++
+	}
+	return text
+}
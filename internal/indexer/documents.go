@@ -0,0 +1,162 @@
+package indexer
+
+import (
+	"time"
+
+	"freescholar-backend/internal/models"
+	"freescholar-backend/pkg/elasticsearch"
+
+	"gorm.io/gorm"
+)
+
+// Index aliases written by the worker and read by the handlers
+const (
+	PublicationIndex = elasticsearch.PublicationsAlias
+	AuthorIndex      = elasticsearch.AuthorsAlias
+)
+
+// LoadPublicationDocument builds the search document of a publication from
+// MySQL, including the text of its PDF
+func LoadPublicationDocument(db *gorm.DB, id uint) (*models.PublicationSearch, error) {
+	var publication models.Publication
+	if err := db.First(&publication, id).Error; err != nil {
+		return nil, err
+	}
+
+	docs, err := LoadPublicationDocuments(db, []models.Publication{publication})
+	if err != nil {
+		return nil, err
+	}
+	if err := loadFulltext(db, docs); err != nil {
+		return nil, err
+	}
+	return &docs[0], nil
+}
+
+// LoadPublicationDocuments builds search documents for a batch of publications,
+// loading keywords and byline-ordered author names with one query each
+func LoadPublicationDocuments(db *gorm.DB, publications []models.Publication) ([]models.PublicationSearch, error) {
+	if len(publications) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(publications))
+	for i, publication := range publications {
+		ids[i] = publication.ID
+	}
+
+	var authorRows []struct {
+		PublicationID uint
+		Name          string
+	}
+	err := db.Table("publication_authors").
+		Select("publication_authors.publication_id, authors.name").
+		Joins("JOIN authors ON authors.id = publication_authors.author_id AND authors.deleted_at IS NULL").
+		Where("publication_authors.publication_id IN ? AND publication_authors.deleted_at IS NULL", ids).
+		Order("publication_authors.publication_id, publication_authors.`order`").
+		Scan(&authorRows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var keywordRows []struct {
+		PublicationID uint
+		Name          string
+	}
+	err = db.Table("publication_keywords").
+		Select("publication_keywords.publication_id, keywords.name").
+		Joins("JOIN keywords ON keywords.id = publication_keywords.keyword_id AND keywords.deleted_at IS NULL").
+		Where("publication_keywords.publication_id IN ?", ids).
+		Scan(&keywordRows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	authors := make(map[uint][]models.Author)
+	for _, row := range authorRows {
+		authors[row.PublicationID] = append(authors[row.PublicationID], models.Author{Name: row.Name})
+	}
+	keywords := make(map[uint][]models.Keyword)
+	for _, row := range keywordRows {
+		keywords[row.PublicationID] = append(keywords[row.PublicationID], models.Keyword{Name: row.Name})
+	}
+
+	docs := make([]models.PublicationSearch, len(publications))
+	for i, publication := range publications {
+		publication.Authors = authors[publication.ID]
+		publication.Keywords = keywords[publication.ID]
+		docs[i] = PublicationDocument(publication)
+	}
+
+	return docs, nil
+}
+
+// loadFulltext fills in the extracted text of the most recently uploaded
+// public PDF of each document; text of private files must not show up in
+// search highlights. Only indexing needs it, so LoadPublicationDocuments
+// leaves it out.
+func loadFulltext(db *gorm.DB, docs []models.PublicationSearch) error {
+	ids := make([]uint, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+
+	var rows []struct {
+		PublicationID uint
+		Text          string
+	}
+	err := db.Model(&models.File{}).
+		Select("publication_id, text").
+		Where("publication_id IN ? AND text_status = ?", ids, models.TextDone).
+		Where("visibility = ? OR (visibility = ? AND embargo_until <= ?)", models.VisibilityPublic, models.VisibilityEmbargoed, time.Now()).
+		Order("id ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	texts := make(map[uint]string, len(rows))
+	for _, row := range rows {
+		texts[row.PublicationID] = row.Text
+	}
+	for i := range docs {
+		docs[i].Fulltext = texts[docs[i].ID]
+	}
+	return nil
+}
+
+// PublicationDocument creates a search model of the publication
+func PublicationDocument(publication models.Publication) models.PublicationSearch {
+	var authors []string
+	for _, author := range publication.Authors {
+		authors = append(authors, author.Name)
+	}
+
+	var keywords []string
+	for _, keyword := range publication.Keywords {
+		keywords = append(keywords, keyword.Name)
+	}
+
+	return models.PublicationSearch{
+		ID:              publication.ID,
+		Title:           publication.Title,
+		Abstract:        publication.Abstract,
+		Authors:         authors,
+		Keywords:        keywords,
+		DOI:             string(publication.DOI),
+		PublicationDate: publication.PublicationDate,
+		Journal:         publication.Journal,
+		CitationCount:   publication.CitationCount,
+		HasPDF:          publication.PDFPath != "",
+	}
+}
+
+// AuthorDocument creates a search model of the author
+func AuthorDocument(author models.Author) models.AuthorSearch {
+	return models.AuthorSearch{
+		ID:          author.ID,
+		Name:        author.Name,
+		Institution: author.Institution,
+		Email:       author.Email,
+	}
+}
@@ -0,0 +1,72 @@
+package indexer
+
+import (
+	"time"
+
+	"freescholar-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Entities mirrored into Elasticsearch
+const (
+	EntityPublication = "publication"
+	EntityAuthor      = "author"
+)
+
+// Outbox actions
+const (
+	ActionIndex  = "index"
+	ActionDelete = "delete"
+)
+
+// Enqueue records a search index change. Call it with the transaction that makes
+// the MySQL change so both commit or roll back together.
+func Enqueue(tx *gorm.DB, entity string, id uint, action string) error {
+	return tx.Create(&models.SearchOutbox{
+		Entity:        entity,
+		EntityID:      id,
+		Action:        action,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+// IndexPublications schedules publications to be (re)indexed
+func IndexPublications(tx *gorm.DB, ids ...uint) error {
+	for _, id := range ids {
+		if err := Enqueue(tx, EntityPublication, id, ActionIndex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeletePublication schedules a publication to be removed from the index
+func DeletePublication(tx *gorm.DB, id uint) error {
+	return Enqueue(tx, EntityPublication, id, ActionDelete)
+}
+
+// IndexAuthor schedules an author to be (re)indexed
+func IndexAuthor(tx *gorm.DB, id uint) error {
+	return Enqueue(tx, EntityAuthor, id, ActionIndex)
+}
+
+// DeleteAuthor schedules an author to be removed from the index
+func DeleteAuthor(tx *gorm.DB, id uint) error {
+	return Enqueue(tx, EntityAuthor, id, ActionDelete)
+}
+
+// Requeue gives dead-lettered events a fresh set of attempts
+func Requeue(db *gorm.DB, ids ...uint) (int64, error) {
+	query := db.Model(&models.SearchOutbox{}).Where("dead_at IS NOT NULL")
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"dead_at":         nil,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+	})
+	return result.RowsAffected, result.Error
+}
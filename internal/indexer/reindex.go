@@ -0,0 +1,431 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"freescholar-backend/internal/models"
+	"freescholar-backend/pkg/elasticsearch"
+
+	"github.com/olivere/elastic/v7"
+	"gorm.io/gorm"
+)
+
+// ReindexOptions configures a full rebuild of an index
+type ReindexOptions struct {
+	// Index is the alias to rebuild, "publications" by default
+	Index     string
+	BatchSize int
+	// StatePath is where progress is checkpointed after every batch
+	StatePath string
+	// Resume continues an interrupted run recorded in StatePath
+	Resume bool
+	// KeepOld leaves the previous index in place after the alias swap
+	KeepOld bool
+	// Retention is how long processed outbox events are kept, see OutboxRetention.
+	// A run must finish within it to catch up on the writes made meanwhile.
+	Retention time.Duration
+	Out       io.Writer
+}
+
+// reindexState is the checkpoint written after every batch
+type reindexState struct {
+	Alias     string    `json:"alias"`
+	Index     string    `json:"index"`
+	LastID    uint      `json:"last_id"`
+	Indexed   int64     `json:"indexed"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// reindexSource reads the rows behind an index from MySQL
+type reindexSource struct {
+	model interface{}
+	// entity is the search outbox entity of the rows
+	entity string
+	// load builds the documents of the live rows among ids, keyed by ID
+	load func(db *gorm.DB, ids []uint) (map[uint]interface{}, error)
+}
+
+var reindexSources = map[string]reindexSource{
+	PublicationIndex: {model: &models.Publication{}, entity: EntityPublication, load: loadPublicationDocuments},
+	AuthorIndex:      {model: &models.Author{}, entity: EntityAuthor, load: loadAuthorDocuments},
+}
+
+// Reindexer rebuilds an index from MySQL into a new versioned index and
+// swaps the alias over once the copy is verified
+type Reindexer struct {
+	db       *gorm.DB
+	esClient *elasticsearch.Client
+	spec     elasticsearch.IndexSpec
+	source   reindexSource
+	opts     ReindexOptions
+}
+
+// NewReindexer creates a new reindexer
+func NewReindexer(db *gorm.DB, esClient *elasticsearch.Client, opts ReindexOptions) (*Reindexer, error) {
+	if opts.Index == "" {
+		opts.Index = PublicationIndex
+	}
+	spec, ok := elasticsearch.SpecFor(opts.Index)
+	source, known := reindexSources[opts.Index]
+	if !ok || !known {
+		return nil, fmt.Errorf("unknown index %q", opts.Index)
+	}
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 1000
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = 72 * time.Hour
+	}
+	if opts.Out == nil {
+		opts.Out = io.Discard
+	}
+	return &Reindexer{db: db, esClient: esClient, spec: spec, source: source, opts: opts}, nil
+}
+
+// Run performs the reindex
+func (r *Reindexer) Run(ctx context.Context) error {
+	state, err := r.prepare(ctx)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	if err := r.db.Model(r.source.model).Count(&total).Error; err != nil {
+		return fmt.Errorf("failed to count rows: %w", err)
+	}
+	r.logf("Indexing %d %s into %s (starting after ID %d)", total, r.spec.Alias, state.Index, state.LastID)
+
+	if err := r.copyAll(ctx, state, total); err != nil {
+		return err
+	}
+
+	// Restore the settings relaxed for bulk loading and make everything searchable
+	if _, err := r.esClient.IndexPutSettings(state.Index).
+		BodyJson(map[string]interface{}{"index": map[string]interface{}{"refresh_interval": "1s", "number_of_replicas": 1}}).
+		Do(ctx); err != nil {
+		return fmt.Errorf("failed to restore index settings: %w", err)
+	}
+
+	// Pick up writes that reached MySQL while the copy ran
+	verifyStart := time.Now()
+	if err := r.catchUp(ctx, state.Index, state.StartedAt); err != nil {
+		return err
+	}
+
+	if err := r.verify(ctx, state.Index); err != nil {
+		return err
+	}
+
+	previous, err := r.esClient.SwapAlias(ctx, r.spec.Alias, state.Index)
+	if err != nil {
+		return err
+	}
+	r.logf("Alias %s now points at %s", r.spec.Alias, state.Index)
+
+	// Writes between verification and the swap went to the old index only
+	if err := r.catchUp(ctx, state.Index, verifyStart); err != nil {
+		return err
+	}
+
+	if !r.opts.KeepOld && len(previous) > 0 {
+		if _, err := r.esClient.DeleteIndex(previous...).Do(ctx); err != nil {
+			return fmt.Errorf("failed to delete old indices %v: %w", previous, err)
+		}
+		r.logf("Deleted old indices %v", previous)
+	}
+
+	if err := os.Remove(r.opts.StatePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove state file: %w", err)
+	}
+
+	r.logf("Reindex finished")
+	return nil
+}
+
+// prepare loads the checkpoint of an interrupted run or creates a new index
+func (r *Reindexer) prepare(ctx context.Context) (*reindexState, error) {
+	data, err := os.ReadFile(r.opts.StatePath)
+	switch {
+	case err == nil && !r.opts.Resume:
+		return nil, fmt.Errorf("an interrupted reindex is recorded in %s; pass -resume to continue it or delete the file to start over", r.opts.StatePath)
+	case err == nil:
+		var state reindexState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("failed to read state file: %w", err)
+		}
+		if state.Alias != r.spec.Alias {
+			return nil, fmt.Errorf("the state file records a reindex of %s, not %s", state.Alias, r.spec.Alias)
+		}
+		exists, err := r.esClient.IndexExists(state.Index).Do(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check index %s: %w", state.Index, err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("index %s from the state file no longer exists; delete %s to start over", state.Index, r.opts.StatePath)
+		}
+		r.logf("Resuming reindex into %s after ID %d", state.Index, state.LastID)
+		return &state, nil
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	case r.opts.Resume:
+		return nil, fmt.Errorf("nothing to resume: %s does not exist", r.opts.StatePath)
+	}
+
+	state := &reindexState{
+		Alias:     r.spec.Alias,
+		Index:     r.spec.VersionedIndexName(),
+		StartedAt: time.Now(),
+	}
+
+	// Bulk loading is much faster without refreshes and replicas
+	err = r.esClient.CreateIndexFromSpec(ctx, r.spec, state.Index, map[string]interface{}{
+		"refresh_interval":   "-1",
+		"number_of_replicas": 0,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.logf("Created index %s", state.Index)
+	return state, r.save(state)
+}
+
+// copyAll streams rows in ID order through the bulk API, checkpointing after every batch
+func (r *Reindexer) copyAll(ctx context.Context, state *reindexState, total int64) error {
+	started := time.Now()
+	startIndexed := state.Indexed
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var ids []uint
+		err := r.db.Model(r.source.model).
+			Where("id > ?", state.LastID).
+			Order("id ASC").
+			Limit(r.opts.BatchSize).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", r.spec.Alias, err)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := r.apply(ctx, state.Index, ids); err != nil {
+			return err
+		}
+
+		state.LastID = ids[len(ids)-1]
+		state.Indexed += int64(len(ids))
+		if err := r.save(state); err != nil {
+			return err
+		}
+
+		r.progress(state.Indexed, startIndexed, total, started)
+	}
+}
+
+// catchUp re-applies the rows of every search outbox event recorded since
+// the given time. Events cover all writes that affect documents, including
+// those that do not touch updated_at such as citation recounts, author
+// renames and hard deletes.
+func (r *Reindexer) catchUp(ctx context.Context, index string, since time.Time) error {
+	if time.Since(since) > r.opts.Retention {
+		return fmt.Errorf("the reindex started %s ago, but processed search outbox events are only kept for %s; delete %s to start over",
+			time.Since(since).Round(time.Minute), r.opts.Retention, r.opts.StatePath)
+	}
+
+	var lastEventID uint
+	applied := 0
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var events []models.SearchOutbox
+		err := r.db.Select("id", "entity_id").
+			Where("entity = ? AND created_at >= ? AND id > ?", r.source.entity, since, lastEventID).
+			Order("id ASC").
+			Limit(r.opts.BatchSize).
+			Find(&events).Error
+		if err != nil {
+			return fmt.Errorf("failed to load search outbox events: %w", err)
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		seen := make(map[uint]bool, len(events))
+		var ids []uint
+		for _, event := range events {
+			if !seen[event.EntityID] {
+				seen[event.EntityID] = true
+				ids = append(ids, event.EntityID)
+			}
+		}
+
+		if err := r.apply(ctx, index, ids); err != nil {
+			return err
+		}
+
+		lastEventID = events[len(events)-1].ID
+		applied += len(ids)
+	}
+
+	if applied > 0 {
+		r.logf("Caught up on %d %s changed during the reindex", applied, r.spec.Alias)
+	}
+	return nil
+}
+
+// apply indexes the live rows among ids and deletes the rest from the index
+func (r *Reindexer) apply(ctx context.Context, index string, ids []uint) error {
+	docs, err := r.source.load(r.db, ids)
+	if err != nil {
+		return fmt.Errorf("failed to load %s details: %w", r.spec.Alias, err)
+	}
+
+	bulk := r.esClient.Bulk().Index(index)
+	for _, id := range ids {
+		if doc, ok := docs[id]; ok {
+			bulk.Add(elastic.NewBulkIndexRequest().Id(strconv.Itoa(int(id))).Doc(doc))
+		} else {
+			bulk.Add(elastic.NewBulkDeleteRequest().Id(strconv.Itoa(int(id))))
+		}
+	}
+
+	return r.doBulk(ctx, bulk)
+}
+
+// verify compares the document count of the new index with MySQL
+func (r *Reindexer) verify(ctx context.Context, index string) error {
+	if _, err := r.esClient.Refresh(index).Do(ctx); err != nil {
+		return fmt.Errorf("failed to refresh index: %w", err)
+	}
+
+	var expected int64
+	if err := r.db.Model(r.source.model).Count(&expected).Error; err != nil {
+		return fmt.Errorf("failed to count rows: %w", err)
+	}
+
+	actual, err := r.esClient.Count(index).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count documents: %w", err)
+	}
+
+	if actual != expected {
+		return fmt.Errorf("count mismatch: MySQL has %d %s but %s has %d documents; the alias was not swapped", expected, r.spec.Alias, index, actual)
+	}
+
+	r.logf("Verified %d documents in %s", actual, index)
+	return nil
+}
+
+// doBulk executes a bulk request and fails on any rejected item
+func (r *Reindexer) doBulk(ctx context.Context, bulk *elastic.BulkService) error {
+	if bulk.NumberOfActions() == 0 {
+		return nil
+	}
+
+	response, err := bulk.Do(ctx)
+	if err != nil {
+		return fmt.Errorf("bulk request failed: %w", err)
+	}
+
+	for _, item := range response.Failed() {
+		if item.Status == 404 {
+			continue // deleting something that was never indexed
+		}
+		reason := "unknown error"
+		if item.Error != nil {
+			reason = item.Error.Type + ": " + item.Error.Reason
+		}
+		return fmt.Errorf("failed to index document %s: %s", item.Id, reason)
+	}
+
+	return nil
+}
+
+// save writes the checkpoint atomically
+func (r *Reindexer) save(state *reindexState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp := r.opts.StatePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return os.Rename(tmp, r.opts.StatePath)
+}
+
+// progress prints how far the copy has got and an estimate of the time left
+func (r *Reindexer) progress(indexed, startIndexed, total int64, started time.Time) {
+	elapsed := time.Since(started)
+	done := indexed - startIndexed
+	rate := float64(done) / elapsed.Seconds()
+
+	percent := 100.0
+	if total > 0 {
+		percent = float64(indexed) / float64(total) * 100
+	}
+
+	eta := "unknown"
+	if rate > 0 && total > indexed {
+		eta = (time.Duration(float64(total-indexed)/rate) * time.Second).Round(time.Second).String()
+	}
+
+	r.logf("%d/%d (%.1f%%) %.0f docs/s, ETA %s", indexed, total, percent, rate, eta)
+}
+
+func (r *Reindexer) logf(format string, args ...interface{}) {
+	fmt.Fprintf(r.opts.Out, time.Now().Format("15:04:05")+" "+format+"\n", args...)
+}
+
+// loadPublicationDocuments builds the publication documents of the live rows among ids
+func loadPublicationDocuments(db *gorm.DB, ids []uint) (map[uint]interface{}, error) {
+	var publications []models.Publication
+	if err := db.Where("id IN ?", ids).Find(&publications).Error; err != nil {
+		return nil, err
+	}
+
+	docs, err := LoadPublicationDocuments(db, publications)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) > 0 {
+		if err := loadFulltext(db, docs); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make(map[uint]interface{}, len(docs))
+	for _, doc := range docs {
+		result[doc.ID] = doc
+	}
+	return result, nil
+}
+
+// loadAuthorDocuments builds the author documents of the live rows among ids
+func loadAuthorDocuments(db *gorm.DB, ids []uint) (map[uint]interface{}, error) {
+	var authors []models.Author
+	if err := db.Where("id IN ?", ids).Find(&authors).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint]interface{}, len(authors))
+	for _, author := range authors {
+		result[author.ID] = AuthorDocument(author)
+	}
+	return result, nil
+}
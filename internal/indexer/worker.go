@@ -0,0 +1,264 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"freescholar-backend/config"
+	"freescholar-backend/internal/models"
+	"freescholar-backend/pkg/elasticsearch"
+
+	"github.com/olivere/elastic/v7"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// claimLease keeps claimed events away from other workers while they are processed
+	claimLease = time.Minute
+	// Retry delays grow from baseBackoff up to maxBackoff
+	baseBackoff = 2 * time.Second
+	maxBackoff  = 10 * time.Minute
+)
+
+// Worker drains the search outbox into Elasticsearch
+type Worker struct {
+	db           *gorm.DB
+	esClient     *elasticsearch.Client
+	batchSize    int
+	pollInterval time.Duration
+	maxAttempts  int
+	retention    time.Duration
+}
+
+// NewWorker creates a new outbox worker
+func NewWorker(db *gorm.DB, esClient *elasticsearch.Client, cfg config.ESConfig) *Worker {
+	w := &Worker{
+		db:           db,
+		esClient:     esClient,
+		batchSize:    cfg.OutboxBatchSize,
+		pollInterval: time.Duration(cfg.OutboxPollInterval) * time.Second,
+		maxAttempts:  cfg.OutboxMaxAttempts,
+		retention:    OutboxRetention(cfg),
+	}
+
+	if w.batchSize < 1 {
+		w.batchSize = 100
+	}
+	if w.pollInterval <= 0 {
+		w.pollInterval = 2 * time.Second
+	}
+	if w.maxAttempts < 1 {
+		w.maxAttempts = 10
+	}
+
+	return w
+}
+
+// OutboxRetention returns how long processed outbox events are kept
+func OutboxRetention(cfg config.ESConfig) time.Duration {
+	if cfg.OutboxRetention < 1 {
+		return 72 * time.Hour
+	}
+	return time.Duration(cfg.OutboxRetention) * time.Hour
+}
+
+// Run processes the outbox until the context is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Prune(); err != nil {
+			log.Printf("Search outbox: %v", err)
+		}
+
+		// Keep draining while full batches come back
+		for {
+			processed, err := w.ProcessBatch(ctx)
+			if err != nil {
+				log.Printf("Search outbox: %v", err)
+				break
+			}
+			if processed < w.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch claims due events, applies them to Elasticsearch in one bulk
+// request and records the outcome. It returns the number of events claimed.
+func (w *Worker) ProcessBatch(ctx context.Context) (int, error) {
+	events, err := w.claim()
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim events: %w", err)
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	failures := make(map[uint]string)
+	bulk := w.esClient.Bulk()
+	var pending []models.SearchOutbox
+
+	for _, event := range events {
+		request, err := w.buildRequest(event)
+		if err != nil {
+			failures[event.ID] = err.Error()
+			continue
+		}
+		bulk.Add(request)
+		pending = append(pending, event)
+	}
+
+	if len(pending) > 0 {
+		response, err := bulk.Do(ctx)
+		if err != nil {
+			for _, event := range pending {
+				failures[event.ID] = err.Error()
+			}
+		} else {
+			for i, item := range response.Items {
+				for _, result := range item {
+					if result.Error != nil && !(result.Status == 404 && pending[i].Action == ActionDelete) {
+						failures[pending[i].ID] = result.Error.Type + ": " + result.Error.Reason
+					}
+				}
+			}
+		}
+	}
+
+	// Mark what succeeded as processed, reschedule or dead-letter what failed
+	var done []uint
+	for _, event := range events {
+		reason, failed := failures[event.ID]
+		if !failed {
+			done = append(done, event.ID)
+			continue
+		}
+		if err := w.fail(event, reason); err != nil {
+			return len(events), fmt.Errorf("failed to record failure of event %d: %w", event.ID, err)
+		}
+	}
+
+	if len(done) > 0 {
+		if err := w.db.Model(&models.SearchOutbox{}).Where("id IN ?", done).Update("processed_at", time.Now()).Error; err != nil {
+			return len(events), fmt.Errorf("failed to mark processed events: %w", err)
+		}
+	}
+
+	return len(events), nil
+}
+
+// Prune deletes processed events older than the retention period
+func (w *Worker) Prune() error {
+	err := w.db.Where("processed_at < ?", time.Now().Add(-w.retention)).Delete(&models.SearchOutbox{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to prune processed events: %w", err)
+	}
+	return nil
+}
+
+// claim locks a batch of due events for this worker
+func (w *Worker) claim() ([]models.SearchOutbox, error) {
+	var events []models.SearchOutbox
+	now := time.Now()
+
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("dead_at IS NULL AND processed_at IS NULL AND next_attempt_at <= ?", now).
+			Order("id ASC").
+			Limit(w.batchSize).
+			Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+		}
+
+		return tx.Model(&models.SearchOutbox{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(claimLease)).Error
+	})
+
+	return events, err
+}
+
+// buildRequest turns an event into a bulk request using the current MySQL state.
+// Records that no longer exist are removed from the index.
+func (w *Worker) buildRequest(event models.SearchOutbox) (elastic.BulkableRequest, error) {
+	id := strconv.Itoa(int(event.EntityID))
+
+	switch event.Entity {
+	case EntityPublication:
+		if event.Action == ActionIndex {
+			doc, err := LoadPublicationDocument(w.db, event.EntityID)
+			if err == nil {
+				return elastic.NewBulkIndexRequest().Index(PublicationIndex).Id(id).Doc(doc), nil
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+		}
+		return elastic.NewBulkDeleteRequest().Index(PublicationIndex).Id(id), nil
+
+	case EntityAuthor:
+		if event.Action == ActionIndex {
+			var author models.Author
+			err := w.db.First(&author, event.EntityID).Error
+			if err == nil {
+				return elastic.NewBulkIndexRequest().Index(AuthorIndex).Id(id).Doc(AuthorDocument(author)), nil
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+		}
+		return elastic.NewBulkDeleteRequest().Index(AuthorIndex).Id(id), nil
+	}
+
+	return nil, fmt.Errorf("unknown entity %q", event.Entity)
+}
+
+// fail records a failed attempt, moving the event to the dead letters once it
+// has used up its attempts
+func (w *Worker) fail(event models.SearchOutbox, reason string) error {
+	attempts := event.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":        attempts,
+		"last_error":      reason,
+		"next_attempt_at": time.Now().Add(backoff(attempts)),
+	}
+
+	if attempts >= w.maxAttempts {
+		updates["dead_at"] = time.Now()
+		log.Printf("Search outbox: %s %s %d dead after %d attempts: %s", event.Action, event.Entity, event.EntityID, attempts, reason)
+	}
+
+	return w.db.Model(&event).Updates(updates).Error
+}
+
+// backoff returns the delay before the given retry attempt
+func backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
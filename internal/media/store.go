@@ -0,0 +1,99 @@
+// Package media stores uploaded files in a storage backend. Files are
+// addressed by the SHA-256 of their content, so uploading the same bytes
+// twice stores them once.
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"freescholar-backend/pkg/storage"
+)
+
+// ErrTooLarge is returned by Save when the content exceeds the size limit
+var ErrTooLarge = errors.New("file too large")
+
+// Blob is stored content
+type Blob struct {
+	// Path is the storage key, e.g. pdf/3a/3a7bd3...e4.pdf
+	Path     string
+	Checksum string
+	Size     int64
+	// Created is false if identical content was already stored
+	Created bool
+}
+
+// Store keeps content-addressed files in a storage backend
+type Store struct {
+	backend storage.Storage
+}
+
+// NewStore creates a store on top of a storage backend
+func NewStore(backend storage.Storage) *Store {
+	return &Store{backend: backend}
+}
+
+// Save copies up to limit bytes from r into the store under dir, naming the
+// file after its checksum with the given extension
+func (s *Store) Save(ctx context.Context, dir, ext, contentType string, r io.Reader, limit int64) (Blob, error) {
+	// Spool to a temporary file first; the key is only known once all content is hashed
+	tmp, err := os.CreateTemp("", "freescholar-upload-*")
+	if err != nil {
+		return Blob{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(r, limit+1))
+	if err != nil {
+		return Blob{}, err
+	}
+	if size > limit {
+		return Blob{}, ErrTooLarge
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	blob := Blob{
+		Path:     path.Join(dir, checksum[:2], checksum+ext),
+		Checksum: checksum,
+		Size:     size,
+	}
+
+	if _, err := s.backend.Stat(ctx, blob.Path); err == nil {
+		return blob, nil
+	} else if !errors.Is(err, storage.ErrNotExist) {
+		return Blob{}, err
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return Blob{}, err
+	}
+	if err := s.backend.Put(ctx, blob.Path, tmp, size, contentType); err != nil {
+		return Blob{}, err
+	}
+	blob.Created = true
+	return blob, nil
+}
+
+// Open opens a stored file for reading
+func (s *Store) Open(ctx context.Context, path string) (storage.Object, error) {
+	return s.backend.Get(ctx, path)
+}
+
+// Remove deletes a stored file; missing files are not an error
+func (s *Store) Remove(ctx context.Context, path string) error {
+	return s.backend.Delete(ctx, path)
+}
+
+// PresignGet returns a URL downloading a stored file directly from the
+// backend, or storage.ErrPresignUnsupported if it cannot hand out URLs
+func (s *Store) PresignGet(ctx context.Context, path string, expires time.Duration, headers storage.ResponseHeaders) (string, error) {
+	return s.backend.PresignGet(ctx, path, expires, headers)
+}
@@ -0,0 +1,173 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"freescholar-backend/internal/bibliography"
+	"freescholar-backend/internal/models"
+)
+
+// crossrefTypes maps Crossref work types onto publication types
+var crossrefTypes = map[string]string{
+	"journal-article":     models.PublicationTypeArticle,
+	"proceedings-article": models.PublicationTypeConference,
+	"book":                models.PublicationTypeBook,
+	"monograph":           models.PublicationTypeBook,
+	"edited-book":         models.PublicationTypeBook,
+	"reference-book":      models.PublicationTypeBook,
+	"book-chapter":        models.PublicationTypeBook,
+}
+
+// Crossref resolves DOIs with the Crossref REST API or a service with the same /works/{doi} interface
+type Crossref struct {
+	baseURL   string
+	userAgent string
+	client    *http.Client
+}
+
+// NewCrossref creates a Crossref client. mailto is sent in the User-Agent,
+// which Crossref uses to route requests to its faster "polite" pool.
+func NewCrossref(baseURL, mailto string, timeout time.Duration) *Crossref {
+	userAgent := "FreeScholar/1.0"
+	if mailto != "" {
+		userAgent += " (mailto:" + mailto + ")"
+	}
+	return &Crossref{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		userAgent: userAgent,
+		client:    &http.Client{Timeout: timeout},
+	}
+}
+
+// crossrefWork is the part of a Crossref work record we use
+type crossrefWork struct {
+	DOI            string   `json:"DOI"`
+	Type           string   `json:"type"`
+	Title          []string `json:"title"`
+	Abstract       string   `json:"abstract"`
+	ContainerTitle []string `json:"container-title"`
+	Volume         string   `json:"volume"`
+	Issue          string   `json:"issue"`
+	Page           string   `json:"page"`
+	Publisher      string   `json:"publisher"`
+	URL            string   `json:"URL"`
+	Subject        []string `json:"subject"`
+	Author         []struct {
+		Given  string `json:"given"`
+		Family string `json:"family"`
+		Name   string `json:"name"`
+	} `json:"author"`
+	// Dates in order of preference
+	PublishedPrint  *crossrefDate `json:"published-print"`
+	PublishedOnline *crossrefDate `json:"published-online"`
+	Issued          *crossrefDate `json:"issued"`
+}
+
+type crossrefDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+// Resolve fetches the work record of a DOI
+func (c *Crossref) Resolve(ctx context.Context, doi string) (bibliography.Record, error) {
+	var record bibliography.Record
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/works/"+url.PathEscape(doi), nil)
+	if err != nil {
+		return record, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return record, fmt.Errorf("crossref request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return record, ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		io.Copy(io.Discard, resp.Body)
+		return record, fmt.Errorf("crossref returned %s", resp.Status)
+	}
+
+	var body struct {
+		Message crossrefWork `json:"message"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(&body); err != nil {
+		return record, fmt.Errorf("invalid crossref response: %w", err)
+	}
+
+	return body.Message.record(doi), nil
+}
+
+func (w crossrefWork) record(doi string) bibliography.Record {
+	record := bibliography.Record{
+		Type:      crossrefTypes[w.Type],
+		DOI:       models.ExtractDOI(w.DOI),
+		Abstract:  stripJATS(w.Abstract),
+		Volume:    w.Volume,
+		Issue:     w.Issue,
+		Pages:     w.Page,
+		Publisher: w.Publisher,
+		URL:       w.URL,
+		Keywords:  w.Subject,
+	}
+	if record.Type == "" {
+		record.Type = models.PublicationTypeOther
+	}
+	if record.DOI == "" {
+		record.DOI = doi
+	}
+	if len(w.Title) > 0 {
+		record.Title = strings.Join(strings.Fields(w.Title[0]), " ")
+	}
+	if len(w.ContainerTitle) > 0 {
+		record.Container = w.ContainerTitle[0]
+	}
+
+	for _, author := range w.Author {
+		if author.Name != "" {
+			// Organizations carry a single name
+			record.Authors = append(record.Authors, author.Name)
+			continue
+		}
+		record.Authors = append(record.Authors, strings.TrimSpace(author.Given+" "+author.Family))
+	}
+
+	for _, date := range []*crossrefDate{w.PublishedPrint, w.PublishedOnline, w.Issued} {
+		if date == nil || len(date.DateParts) == 0 || len(date.DateParts[0]) == 0 {
+			continue
+		}
+		parts := date.DateParts[0]
+		record.Year = parts[0]
+		if len(parts) > 1 {
+			record.Month = parts[1]
+		}
+		if len(parts) > 2 {
+			record.Day = parts[2]
+		}
+		break
+	}
+
+	return record
+}
+
+var jatsTag = regexp.MustCompile(`<[^>]+>`)
+
+// stripJATS turns a JATS XML abstract into plain text
+func stripJATS(abstract string) string {
+	text := jatsTag.ReplaceAllString(abstract, " ")
+	text = strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+	return strings.TrimPrefix(text, "Abstract ")
+}
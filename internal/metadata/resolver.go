@@ -0,0 +1,17 @@
+// Package metadata looks up publication metadata in external registries
+package metadata
+
+import (
+	"context"
+	"errors"
+
+	"freescholar-backend/internal/bibliography"
+)
+
+// ErrNotFound is returned when a registry does not know a DOI
+var ErrNotFound = errors.New("DOI not found")
+
+// Resolver looks up the metadata of a publication by its DOI
+type Resolver interface {
+	Resolve(ctx context.Context, doi string) (bibliography.Record, error)
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Citation links a citing publication to a publication in its reference list.
+// References that do not match a known publication keep CitedID nil and are
+// resolved by DOI when that publication is added later.
+type Citation struct {
+	gorm.Model
+	CitingID     uint         `json:"citing_id" gorm:"index;not null"`
+	CitedID      *uint        `json:"cited_id" gorm:"index"`
+	Position     int          `json:"position" gorm:"not null;default:0"`
+	RawReference string       `json:"raw_reference" gorm:"type:text"`
+	DOI          string       `json:"doi" gorm:"index;size:255"`
+	Cited        *Publication `json:"cited,omitempty" gorm:"foreignKey:CitedID"`
+}
+
+// doiPattern finds a DOI in free text such as a formatted reference
+var doiPattern = regexp.MustCompile(`(?i)\b10\.\d{4,9}/[^\s"<>]+`)
+
+// ExtractDOI returns the first DOI in text, lowercased, or "" if there is none
+func ExtractDOI(text string) string {
+	doi := doiPattern.FindString(text)
+	return strings.ToLower(strings.TrimRight(doi, ".,;:)]}"))
+}
+
+// NormalizeDOI returns the canonical form of a DOI: lowercased and without
+// resolver prefixes such as https://doi.org/ or doi:
+func NormalizeDOI(doi string) string {
+	if extracted := ExtractDOI(doi); extracted != "" {
+		return extracted
+	}
+	return strings.ToLower(strings.TrimSpace(doi))
+}
+
+// DOI is the DOI column of a publication. DOIs are stored normalized so
+// variants of one DOI collide on the unique index, and an empty DOI is
+// stored as NULL so the index only applies to publications that have one.
+type DOI string
+
+// Value implements driver.Valuer
+func (d DOI) Value() (driver.Value, error) {
+	normalized := NormalizeDOI(string(d))
+	if normalized == "" {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// Scan implements sql.Scanner
+func (d *DOI) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*d = ""
+	case []byte:
+		*d = DOI(v)
+	case string:
+		*d = DOI(v)
+	default:
+		return fmt.Errorf("cannot scan %T into DOI", value)
+	}
+	return nil
+}
+
+// RecountCitations recomputes CitationCount of the given publications from
+// live citations by live publications
+func RecountCitations(db *gorm.DB, publicationIDs ...uint) error {
+	if len(publicationIDs) == 0 {
+		return nil
+	}
+
+	var rows []struct {
+		CitedID uint
+		Count   int
+	}
+	err := db.Table("citations").
+		Select("citations.cited_id, COUNT(DISTINCT citations.citing_id) AS count").
+		Joins("JOIN publications ON publications.id = citations.citing_id AND publications.deleted_at IS NULL").
+		Where("citations.cited_id IN ? AND citations.deleted_at IS NULL", publicationIDs).
+		Group("citations.cited_id").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		counts[row.CitedID] = row.Count
+	}
+
+	for _, id := range publicationIDs {
+		err := db.Model(&Publication{}).Where("id = ?", id).UpdateColumn("citation_count", counts[id]).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ResolveCitations links unresolved references carrying the publication's DOI
+// to it and returns how many were linked
+func ResolveCitations(db *gorm.DB, publication Publication) (int64, error) {
+	doi := NormalizeDOI(string(publication.DOI))
+	if doi == "" {
+		return 0, nil
+	}
+
+	result := db.Model(&Citation{}).
+		Where("cited_id IS NULL AND doi = ? AND citing_id <> ?", doi, publication.ID).
+		Update("cited_id", publication.ID)
+	return result.RowsAffected, result.Error
+}
+
+// CitedIDs returns the resolved references of a publication
+func CitedIDs(db *gorm.DB, publicationID uint) ([]uint, error) {
+	var ids []uint
+	err := db.Model(&Citation{}).
+		Where("citing_id = ? AND cited_id IS NOT NULL", publicationID).
+		Distinct().
+		Pluck("cited_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// SearchOutbox is a pending change to the search index. Rows are written in the
+// same transaction as the MySQL change they mirror and drained by the indexer
+// worker, so Elasticsearch catches up even after an outage. Processed rows are
+// kept for a while so a reindex can replay the changes made while it ran.
+type SearchOutbox struct {
+	ID            uint       `json:"id" gorm:"primarykey"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Entity        string     `json:"entity" gorm:"size:50;not null"` // "publication" or "author"
+	EntityID      uint       `json:"entity_id" gorm:"not null;index"`
+	Action        string     `json:"action" gorm:"size:20;not null"` // "index" or "delete"
+	Attempts      int        `json:"attempts" gorm:"default:0"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index"`
+	LastError     string     `json:"last_error" gorm:"type:text"`
+	DeadAt        *time.Time `json:"dead_at" gorm:"index;default:null"`
+	ProcessedAt   *time.Time `json:"processed_at" gorm:"index;default:null"`
+}
@@ -10,8 +10,9 @@ import (
 type Publication struct {
 	gorm.Model
 	Title           string    `json:"title" gorm:"index;size:512;not null"`
+	Type            string    `json:"type" gorm:"size:20;not null;default:article"`
 	Abstract        string    `json:"abstract" gorm:"type:text"`
-	DOI             string    `json:"doi" gorm:"uniqueIndex;size:255"`
+	DOI             DOI       `json:"doi" gorm:"uniqueIndex;size:255"`
 	PublicationDate time.Time `json:"publication_date" gorm:"index"`
 	Journal         string    `json:"journal" gorm:"index;size:255"`
 	Volume          string    `json:"volume" gorm:"size:50"`
@@ -21,12 +22,21 @@ type Publication struct {
 	CitationCount   int       `json:"citation_count" gorm:"default:0"`
 	URL             string    `json:"url" gorm:"size:512"`
 	PDFPath         string    `json:"pdf_path" gorm:"size:512"`
+	OwnerID         uint      `json:"owner_id" gorm:"index"`
 	
 	// Relationships
 	Authors         []Author         `json:"authors" gorm:"many2many:publication_authors;"`
 	Keywords        []Keyword        `json:"keywords" gorm:"many2many:publication_keywords;"`
 }
 
+// Publication types, matching the entry types of BibTeX and RIS
+const (
+	PublicationTypeArticle    = "article"
+	PublicationTypeConference = "conference"
+	PublicationTypeBook       = "book"
+	PublicationTypeOther      = "other"
+)
+
 // Author represents an author of publications
 type Author struct {
 	gorm.Model
@@ -53,6 +63,16 @@ type PublicationAuthor struct {
 	Order         int  `json:"order" gorm:"not null;default:0"`
 }
 
+// OrderedAuthors returns the authors of a publication in byline order
+func OrderedAuthors(db *gorm.DB, publicationID uint) ([]Author, error) {
+	var authors []Author
+	err := db.Joins("JOIN publication_authors ON publication_authors.author_id = authors.id").
+		Where("publication_authors.publication_id = ? AND publication_authors.deleted_at IS NULL", publicationID).
+		Order("publication_authors.`order` ASC").
+		Find(&authors).Error
+	return authors, err
+}
+
 // ScholarProfile represents a scholar's profile
 type ScholarProfile struct {
 	gorm.Model
@@ -85,17 +105,79 @@ type Message struct {
 	Receiver    User      `json:"receiver" gorm:"foreignKey:ReceiverID"`
 }
 
-// File represents an uploaded file
+// File represents an uploaded file. Files with equal content share one
+// stored copy, found by Checksum.
 type File struct {
 	gorm.Model
-	FileName    string `json:"file_name" gorm:"size:255;not null"`
-	FilePath    string `json:"file_path" gorm:"size:512;not null"`
-	FileSize    int64  `json:"file_size" gorm:"not null"`
-	ContentType string `json:"content_type" gorm:"size:100;not null"`
-	UploaderID  uint   `json:"uploader_id" gorm:"index"`
-	Uploader    User   `json:"uploader" gorm:"foreignKey:UploaderID"`
+	FileName      string `json:"file_name" gorm:"size:255;not null"`
+	FilePath      string `json:"file_path" gorm:"size:512;not null"`
+	FileSize      int64  `json:"file_size" gorm:"not null"`
+	ContentType   string `json:"content_type" gorm:"size:100;not null"`
+	Checksum      string `json:"checksum" gorm:"size:64;index"` // hex SHA-256 of the content
+	UploaderID    uint   `json:"uploader_id" gorm:"index"`
+	Uploader      *User  `json:"uploader,omitempty" gorm:"foreignKey:UploaderID"`
+	PublicationID *uint  `json:"publication_id" gorm:"index"`
+
+	// Who may download the file; embargoed files become public at EmbargoUntil
+	Visibility   string     `json:"visibility" gorm:"size:20;not null;default:public"`
+	EmbargoUntil *time.Time `json:"embargo_until"`
+
+	// Text extracted from the file for full-text search
+	TextStatus string `json:"text_status" gorm:"size:20;index;not null;default:pending"`
+	Text       string `json:"-" gorm:"type:longtext"`
 }
 
+// Visibilities of a File. Private and embargoed files can be downloaded by
+// those who may edit their publication and through signed share links.
+const (
+	VisibilityPublic    = "public"
+	VisibilityPrivate   = "private"
+	VisibilityEmbargoed = "embargoed"
+)
+
+// FileVisibility is the data structure for changing who may download a file
+type FileVisibility struct {
+	Visibility   string     `json:"visibility" binding:"required,oneof=public private embargoed"`
+	EmbargoUntil *time.Time `json:"embargo_until"`
+}
+
+// FileShare is the data structure for creating a share link
+type FileShare struct {
+	ExpiresIn int `json:"expires_in" binding:"omitempty,min=1"` // seconds
+}
+
+// IsPublic reports whether anyone may download the file at the given time
+func (f *File) IsPublic(now time.Time) bool {
+	switch f.Visibility {
+	case VisibilityPrivate:
+		return false
+	case VisibilityEmbargoed:
+		return f.EmbargoUntil != nil && !now.Before(*f.EmbargoUntil)
+	default:
+		return true
+	}
+}
+
+// FileOwnerID returns the owner of the publication a file belongs to, or its
+// uploader if it belongs to none
+func FileOwnerID(db *gorm.DB, file File) (uint, error) {
+	if file.PublicationID == nil {
+		return file.UploaderID, nil
+	}
+	var publication Publication
+	if err := db.Select("id", "owner_id").First(&publication, *file.PublicationID).Error; err != nil {
+		return 0, err
+	}
+	return publication.OwnerID, nil
+}
+
+// Text extraction states of a File
+const (
+	TextPending = "pending"
+	TextDone    = "done"
+	TextFailed  = "failed"
+)
+
 // SearchHistory represents a user's search history
 type SearchHistory struct {
 	gorm.Model
@@ -129,4 +211,16 @@ type PublicationSearch struct {
 	PublicationDate time.Time `json:"publication_date"`
 	Journal         string    `json:"journal"`
 	CitationCount   int       `json:"citation_count"`
-}
\ No newline at end of file
+	HasPDF          bool      `json:"has_pdf"`
+	// Fulltext is the text of the PDF. It is only loaded for indexing and
+	// left out of search results.
+	Fulltext        string    `json:"fulltext,omitempty"`
+}
+
+// AuthorSearch is the model for searching authors in Elasticsearch
+type AuthorSearch struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Institution string `json:"institution"`
+	Email       string `json:"email"`
+}
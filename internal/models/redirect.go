@@ -0,0 +1,37 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// PublicationRedirect points the ID of a publication merged away to the
+// publication that absorbed it. Redirects are kept one hop deep: merging the
+// target again re-points its redirects.
+type PublicationRedirect struct {
+	gorm.Model
+	FromID uint `json:"from_id" gorm:"uniqueIndex;not null"`
+	ToID   uint `json:"to_id" gorm:"index;not null"`
+}
+
+// ResolveRedirect returns the publication a merged publication ID now points to
+func ResolveRedirect(db *gorm.DB, fromID uint) (uint, bool) {
+	var redirect PublicationRedirect
+	result := db.Where("from_id = ?", fromID).Limit(1).Find(&redirect)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return 0, false
+	}
+	return redirect.ToID, true
+}
+
+// AddRedirect records that fromID was merged into toID, re-pointing earlier
+// redirects to fromID so every redirect resolves in one step
+func AddRedirect(tx *gorm.DB, fromID, toID uint) error {
+	if err := tx.Model(&PublicationRedirect{}).Where("to_id = ?", fromID).Update("to_id", toID).Error; err != nil {
+		return err
+	}
+	// A publication merged, restored and merged again may already have a row
+	if err := tx.Unscoped().Where("from_id = ?", fromID).Delete(&PublicationRedirect{}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&PublicationRedirect{FromID: fromID, ToID: toID}).Error
+}
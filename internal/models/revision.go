@@ -0,0 +1,288 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Revision actions
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionRestore = "restore"
+	RevisionMerge   = "merge"
+)
+
+// PublicationRevision records one change to a publication: who made it,
+// which fields changed and the complete state afterwards, so any revision
+// can be restored. Numbers count up from 1 per publication.
+type PublicationRevision struct {
+	gorm.Model
+	PublicationID uint                 `json:"publication_id" gorm:"uniqueIndex:idx_publication_revision;not null"`
+	Number        int                  `json:"number" gorm:"uniqueIndex:idx_publication_revision;not null"`
+	UserID        uint                 `json:"user_id" gorm:"index"`
+	Action        string               `json:"action" gorm:"size:20;not null"`
+	RestoredFrom  *int                 `json:"restored_from,omitempty"`
+	Changes       RevisionChanges      `json:"changes" gorm:"type:json"`
+	Snapshot      *PublicationSnapshot `json:"snapshot,omitempty" gorm:"type:json"`
+}
+
+// FieldChange is the old and new value of a changed field
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// RevisionChanges maps changed fields, by JSON name, to their change
+type RevisionChanges map[string]FieldChange
+
+// Value implements driver.Valuer
+func (c RevisionChanges) Value() (driver.Value, error) {
+	return jsonValue(c)
+}
+
+// Scan implements sql.Scanner
+func (c *RevisionChanges) Scan(value interface{}) error {
+	return jsonScan(value, c)
+}
+
+// PublicationSnapshot is the editable state of a publication
+type PublicationSnapshot struct {
+	Title           string   `json:"title"`
+	Type            string   `json:"type"`
+	Abstract        string   `json:"abstract"`
+	DOI             string   `json:"doi"`
+	PublicationDate string   `json:"publication_date"`
+	Journal         string   `json:"journal"`
+	Volume          string   `json:"volume"`
+	Issue           string   `json:"issue"`
+	Pages           string   `json:"pages"`
+	Publisher       string   `json:"publisher"`
+	URL             string   `json:"url"`
+	Authors         []uint   `json:"authors"`
+	Keywords        []string `json:"keywords"`
+}
+
+// Value implements driver.Valuer
+func (s PublicationSnapshot) Value() (driver.Value, error) {
+	return jsonValue(s)
+}
+
+// Scan implements sql.Scanner
+func (s *PublicationSnapshot) Scan(value interface{}) error {
+	return jsonScan(value, s)
+}
+
+func jsonValue(v interface{}) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func jsonScan(value interface{}, v interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(data, v)
+	case string:
+		return json.Unmarshal([]byte(data), v)
+	default:
+		return fmt.Errorf("cannot scan %T into %T", value, v)
+	}
+}
+
+// LockPublication locks the row of a publication until the transaction ends.
+// Edits take it before their snapshot, so concurrent edits of one publication
+// see each other's changes and number their revisions one after the other.
+func LockPublication(tx *gorm.DB, publicationID uint) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&Publication{}, publicationID).Error
+}
+
+// TakeSnapshot reads the editable state of a publication, with authors in byline order
+func TakeSnapshot(db *gorm.DB, publicationID uint) (PublicationSnapshot, error) {
+	var publication Publication
+	if err := db.First(&publication, publicationID).Error; err != nil {
+		return PublicationSnapshot{}, err
+	}
+
+	snapshot := PublicationSnapshot{
+		Title:     publication.Title,
+		Type:      publication.Type,
+		Abstract:  publication.Abstract,
+		DOI:       string(publication.DOI),
+		Journal:   publication.Journal,
+		Volume:    publication.Volume,
+		Issue:     publication.Issue,
+		Pages:     publication.Pages,
+		Publisher: publication.Publisher,
+		URL:       publication.URL,
+		Authors:   []uint{},
+		Keywords:  []string{},
+	}
+	if !publication.PublicationDate.IsZero() {
+		snapshot.PublicationDate = publication.PublicationDate.Format("2006-01-02")
+	}
+
+	err := db.Model(&PublicationAuthor{}).
+		Where("publication_id = ?", publicationID).
+		Order("`order` ASC").
+		Pluck("author_id", &snapshot.Authors).Error
+	if err != nil {
+		return snapshot, err
+	}
+
+	err = db.Table("keywords").
+		Joins("JOIN publication_keywords ON publication_keywords.keyword_id = keywords.id").
+		Where("publication_keywords.publication_id = ? AND keywords.deleted_at IS NULL", publicationID).
+		Pluck("keywords.name", &snapshot.Keywords).Error
+	// Sorted here rather than by the database collation so diffs are stable
+	slices.Sort(snapshot.Keywords)
+	return snapshot, err
+}
+
+// Diff returns the fields that differ between two snapshots
+func (s PublicationSnapshot) Diff(after PublicationSnapshot) RevisionChanges {
+	changes := make(RevisionChanges)
+
+	before := reflect.ValueOf(s)
+	next := reflect.ValueOf(after)
+	for i := 0; i < before.NumField(); i++ {
+		from, to := before.Field(i), next.Field(i)
+		if from.Kind() == reflect.Slice && from.Len() == 0 && to.Len() == 0 {
+			// nil and empty lists are the same
+			continue
+		}
+		if !reflect.DeepEqual(from.Interface(), to.Interface()) {
+			name := before.Type().Field(i).Tag.Get("json")
+			changes[name] = FieldChange{From: from.Interface(), To: to.Interface()}
+		}
+	}
+
+	return changes
+}
+
+// RecordRevision stores a revision of a publication changed from before to
+// after, and returns nil if nothing changed. Publications created before
+// revisions were kept first get a baseline revision holding before.
+func RecordRevision(tx *gorm.DB, publicationID, userID uint, action string, before, after PublicationSnapshot, restoredFrom *int) (*PublicationRevision, error) {
+	changes := before.Diff(after)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	var last PublicationRevision
+	result := tx.Where("publication_id = ?", publicationID).Order("number DESC").Limit(1).Find(&last)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		var publication Publication
+		if err := tx.Unscoped().First(&publication, publicationID).Error; err != nil {
+			return nil, err
+		}
+		last = PublicationRevision{
+			PublicationID: publicationID,
+			Number:        1,
+			UserID:        publication.OwnerID,
+			Action:        RevisionCreate,
+			Changes:       PublicationSnapshot{}.Diff(before),
+			Snapshot:      &before,
+		}
+		last.CreatedAt = publication.CreatedAt
+		if err := tx.Create(&last).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	revision := &PublicationRevision{
+		PublicationID: publicationID,
+		Number:        last.Number + 1,
+		UserID:        userID,
+		Action:        action,
+		RestoredFrom:  restoredFrom,
+		Changes:       changes,
+		Snapshot:      &after,
+	}
+	if err := tx.Create(revision).Error; err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// ApplySnapshot writes the fields, authors and keywords of a snapshot that
+// differ from before onto a publication. Keywords are created as needed;
+// authors must exist.
+func ApplySnapshot(tx *gorm.DB, publicationID uint, before, after PublicationSnapshot) error {
+	changes := before.Diff(after)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	updates := make(map[string]interface{})
+	for name, change := range changes {
+		switch name {
+		case "authors", "keywords":
+		case "doi":
+			updates[name] = DOI(change.To.(string))
+		case "publication_date":
+			// Publications always have a date; a missing one is left alone
+			value := change.To.(string)
+			if value == "" {
+				continue
+			}
+			date, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return err
+			}
+			updates[name] = date
+		default:
+			updates[name] = change.To
+		}
+	}
+	if len(updates) > 0 {
+		if err := tx.Model(&Publication{}).Where("id = ?", publicationID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	if _, ok := changes["authors"]; ok {
+		// The join table doubles as the many2many table, so remove the rows for good
+		if err := tx.Unscoped().Where("publication_id = ?", publicationID).Delete(&PublicationAuthor{}).Error; err != nil {
+			return err
+		}
+		for i, authorID := range after.Authors {
+			row := PublicationAuthor{PublicationID: publicationID, AuthorID: authorID, Order: i}
+			if err := tx.Create(&row).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, ok := changes["keywords"]; ok {
+		publication := Publication{Model: gorm.Model{ID: publicationID}}
+		keywords := make([]Keyword, 0, len(after.Keywords))
+		for _, name := range after.Keywords {
+			var keyword Keyword
+			if err := tx.Where(Keyword{Name: name}).FirstOrCreate(&keyword).Error; err != nil {
+				return err
+			}
+			keywords = append(keywords, keyword)
+		}
+		if err := tx.Model(&publication).Association("Keywords").Replace(keywords); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -15,13 +15,39 @@ type User struct {
 	Password        string     `json:"-" gorm:"size:255;not null"`
 	IsActive        bool       `json:"is_active" gorm:"default:true"`
 	IsAdmin         bool       `json:"is_admin" gorm:"default:false"`
+	Role            Role       `json:"role" gorm:"size:20;default:reader"`
 	DateJoined      time.Time  `json:"date_joined" gorm:"not null;default:CURRENT_TIMESTAMP"`
 	LastLogin       *time.Time `json:"last_login" gorm:"default:null"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at" gorm:"default:null"`
 	ProfileImageURL string     `json:"profile_image_url" gorm:"size:255;default:''"`
 	Biography       string     `json:"biography" gorm:"type:text"`
 	Institution     string     `json:"institution" gorm:"size:255"`
 }
 
+// Role is a user's permission level. Owner is not stored on the user; it is
+// granted per record to the user who created it.
+type Role string
+
+const (
+	RoleAdmin   Role = "admin"
+	RoleCurator Role = "curator"
+	RoleOwner   Role = "owner"
+	RoleReader  Role = "reader"
+)
+
+// Action is an operation a user may attempt on a record
+type Action string
+
+const (
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// UserRole is the data structure for changing a user's role
+type UserRole struct {
+	Role Role `json:"role" binding:"required,oneof=admin curator reader"`
+}
+
 // UserRegister is the data structure for user registration
 type UserRegister struct {
 	Username string `json:"username" binding:"required"`
@@ -44,6 +70,60 @@ type UserProfile struct {
 	Institution     string `json:"institution"`
 }
 
+// IsEmailVerified reports whether the user has confirmed their email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// EffectiveRole returns the user's global role, honouring the IsAdmin flag
+func (u *User) EffectiveRole() Role {
+	if u.IsAdmin || u.Role == RoleAdmin {
+		return RoleAdmin
+	}
+	if u.Role == RoleCurator {
+		return RoleCurator
+	}
+	return RoleReader
+}
+
+// HasRole reports whether the user holds one of the given roles. Admins hold every role.
+func (u *User) HasRole(roles ...Role) bool {
+	role := u.EffectiveRole()
+	if role == RoleAdmin {
+		return true
+	}
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleFor returns the user's role with respect to a record owned by ownerID
+func (u *User) RoleFor(ownerID uint) Role {
+	role := u.EffectiveRole()
+	if role != RoleAdmin && u.Owns(ownerID) {
+		return RoleOwner
+	}
+	return role
+}
+
+// Owns reports whether the user is the owner recorded on a record
+func (u *User) Owns(ownerID uint) bool {
+	return ownerID != 0 && ownerID == u.ID
+}
+
+// Can reports whether the user may perform the action on a record owned by ownerID.
+// Admins may do anything, owners may edit or delete their own records, curators
+// may edit any record, and readers may not change records they do not own.
+func (u *User) Can(action Action, ownerID uint) bool {
+	if u.HasRole(RoleAdmin) || u.Owns(ownerID) {
+		return true
+	}
+	return action == ActionUpdate && u.HasRole(RoleCurator)
+}
+
 // BeforeCreate hook is called before creating the user
 func (u *User) BeforeCreate(tx *gorm.DB) error {
 	// Hash password before storing
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestUserPermissions(t *testing.T) {
+	verified := time.Now()
+	user := func(id uint, role Role, isAdmin bool) User {
+		return User{Model: gorm.Model{ID: id}, Role: role, IsAdmin: isAdmin, EmailVerifiedAt: &verified}
+	}
+	unverified := user(6, RoleReader, false)
+	unverified.EmailVerifiedAt = nil
+
+	const ownerID = 3
+	tests := []struct {
+		name      string
+		user      User
+		role      Role // RoleFor the record owned by ownerID
+		curator   bool // HasRole(RoleCurator)
+		canUpdate bool
+		canDelete bool
+	}{
+		{"admin role", user(1, RoleAdmin, false), RoleAdmin, true, true, true},
+		{"admin flag", user(2, RoleReader, true), RoleAdmin, true, true, true},
+		{"owner", user(ownerID, RoleReader, false), RoleOwner, false, true, true},
+		{"curator", user(4, RoleCurator, false), RoleCurator, true, true, false},
+		{"reader", user(5, RoleReader, false), RoleReader, false, false, false},
+		{"unverified reader", unverified, RoleReader, false, false, false},
+		{"unknown role", user(7, Role("superuser"), false), RoleReader, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.RoleFor(ownerID); got != tt.role {
+				t.Errorf("RoleFor() = %q, want %q", got, tt.role)
+			}
+			if got := tt.user.HasRole(RoleCurator); got != tt.curator {
+				t.Errorf("HasRole(curator) = %v, want %v", got, tt.curator)
+			}
+			if got := tt.user.Can(ActionUpdate, ownerID); got != tt.canUpdate {
+				t.Errorf("Can(update) = %v, want %v", got, tt.canUpdate)
+			}
+			if got := tt.user.Can(ActionDelete, ownerID); got != tt.canDelete {
+				t.Errorf("Can(delete) = %v, want %v", got, tt.canDelete)
+			}
+		})
+	}
+}
+
+func TestUserOwnsNothingWithoutOwner(t *testing.T) {
+	// Records created before ownership was tracked have owner 0
+	u := User{Role: RoleReader}
+	if u.Owns(0) || u.Can(ActionUpdate, 0) {
+		t.Error("a user without an ID must not own records without an owner")
+	}
+}
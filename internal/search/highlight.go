@@ -0,0 +1,94 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/olivere/elastic/v7"
+)
+
+// Highlight tags wrapped around matched terms. Fragments are HTML-escaped,
+// so these are the only markup in them.
+const (
+	HighlightPreTag  = "<em>"
+	HighlightPostTag = "</em>"
+)
+
+// highlightFields maps each highlighted subfield to the field it is reported under
+var highlightFields = map[string]string{
+	"title":        "title",
+	"title.cjk":    "title",
+	"abstract":     "abstract",
+	"abstract.cjk": "abstract",
+	"fulltext":     "fulltext",
+	"fulltext.cjk": "fulltext",
+}
+
+func newHighlight(fragmentSize, fragments int) *elastic.Highlight {
+	if fragments < 1 {
+		fragments = 3
+	}
+
+	highlight := elastic.NewHighlight().
+		PreTags(HighlightPreTag).
+		PostTags(HighlightPostTag).
+		Encoder("html").
+		FragmentSize(fragmentSize).
+		NumOfFragments(fragments)
+
+	for field, reported := range highlightFields {
+		hf := elastic.NewHighlighterField(field)
+		if reported == "title" {
+			// Titles are short; return them whole with the matches marked
+			hf = hf.NumOfFragments(0)
+		}
+		highlight = highlight.Fields(hf)
+	}
+
+	return highlight
+}
+
+// mergeHighlight folds the highlights of subfields into their parent field,
+// preferring the parent's own fragments
+func mergeHighlight(raw elastic.SearchHitHighlight) map[string][]string {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	merged := make(map[string][]string)
+	for field, fragments := range raw {
+		reported, ok := highlightFields[field]
+		if !ok {
+			continue
+		}
+		if field == reported || len(merged[reported]) == 0 {
+			merged[reported] = fragments
+		}
+	}
+
+	return merged
+}
+
+// snippet joins the highlighted fragments of the abstract, or falls back to
+// its first size characters cut at a word boundary, escaped like the fragments
+func snippet(abstract string, fragments []string, size int) string {
+	if len(fragments) > 0 {
+		return strings.Join(fragments, " … ")
+	}
+
+	runes := []rune(strings.TrimSpace(abstract))
+	if size <= 0 || len(runes) <= size {
+		return html.EscapeString(string(runes))
+	}
+
+	cut := size
+	for i := size; i > size/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+
+	return html.EscapeString(strings.TrimSpace(string(runes[:cut]))) + " …"
+}
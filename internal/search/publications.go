@@ -0,0 +1,361 @@
+package search
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+
+	"freescholar-backend/internal/models"
+	"freescholar-backend/pkg/elasticsearch"
+
+	"github.com/olivere/elastic/v7"
+)
+
+// Facet names, used both as aggregation names and as keys in the response
+const (
+	FacetJournals = "journals"
+	FacetYears    = "years"
+	FacetAuthors  = "authors"
+	FacetKeywords = "keywords"
+)
+
+// facetSize is the number of buckets returned per terms facet
+const facetSize = 10
+
+// Filters narrow a publication search. Multiple values of the same field
+// are ORed; different fields are ANDed. Journals, Authors and Keywords are
+// exact facet values.
+type Filters struct {
+	Journals     []string
+	Authors      []string
+	Keywords     []string
+	JournalText  string // part of a journal name: "Nature" also matches "Nature Physics"
+	YearFrom     int
+	YearTo       int
+	FromDate     string // YYYY-MM-DD
+	ToDate       string // YYYY-MM-DD
+	MinCitations *int
+	MaxCitations *int
+	HasPDF       *bool
+}
+
+// Empty reports whether no filter is set
+func (f Filters) Empty() bool {
+	return len(f.Journals) == 0 && len(f.Authors) == 0 && len(f.Keywords) == 0 && f.JournalText == "" &&
+		f.YearFrom == 0 && f.YearTo == 0 && f.FromDate == "" && f.ToDate == "" &&
+		f.MinCitations == nil && f.MaxCitations == nil && f.HasPDF == nil
+}
+
+// PublicationRequest is a full-text publication search
+type PublicationRequest struct {
+	// Query is the parsed search text; nil lists everything matching the filters
+	Query   *Query
+	Filters Filters
+	From    int
+	Size    int
+	Facets  bool
+	// FragmentSize enables highlighting of title and abstract matches
+	// with fragments of about this many characters
+	FragmentSize int
+	Fragments    int
+	// SuggestBelow asks for a spelling correction when fewer hits are found
+	SuggestBelow int
+}
+
+// FacetBucket is one value of a facet with the number of matching publications
+type FacetBucket struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// PublicationHit is a matching publication with its relevance and the
+// fragments of title and abstract that matched
+type PublicationHit struct {
+	models.PublicationSearch
+	Score     *float64            `json:"_score"`
+	Highlight map[string][]string `json:"highlight,omitempty"`
+	// Snippet is the highlighted abstract fragments, or the start of the
+	// abstract when it did not match
+	Snippet string `json:"snippet"`
+	// MatchedFulltext is true when the text of the PDF matched the query
+	MatchedFulltext bool `json:"matched_fulltext"`
+}
+
+// PublicationResult is the outcome of a publication search
+type PublicationResult struct {
+	Publications []PublicationHit         `json:"publications"`
+	Total        int64                    `json:"total"`
+	Facets       map[string][]FacetBucket `json:"facets,omitempty"`
+	DidYouMean   *DidYouMean              `json:"did_you_mean,omitempty"`
+}
+
+// NewPublicationSearch builds the Elasticsearch request for a publication search.
+//
+// Filters on faceted fields go into the post_filter rather than the query, and
+// each facet is counted with every filter except its own. Selecting a journal
+// therefore narrows the hits and the other facets while the journal facet
+// still lists the alternatives.
+func NewPublicationSearch(esClient *elasticsearch.Client, req PublicationRequest) *elastic.SearchService {
+	query := elastic.NewBoolQuery()
+	if req.Query != nil {
+		query.Must(req.Query.ESQuery())
+	} else {
+		query.Must(elastic.NewMatchAllQuery())
+	}
+	query.Filter(req.Filters.plainFilters()...)
+
+	facetFilters := req.Filters.facetFilters()
+
+	service := esClient.Search().
+		Index(elasticsearch.PublicationsAlias).
+		Query(query).
+		PostFilter(combine(facetFilters, "")).
+		From(req.From).
+		Size(req.Size).
+		FetchSourceContext(elastic.NewFetchSourceContext(true).Exclude("fulltext")).
+		TrackTotalHits(true)
+
+	if req.Query != nil {
+		service = service.Sort("_score", false) // Sort by relevance
+	}
+	service = service.Sort("publication_date", false) // Then by date (newest first)
+
+	if req.Query != nil && req.FragmentSize > 0 {
+		service = service.Highlight(newHighlight(req.FragmentSize, req.Fragments))
+	}
+
+	if text := req.suggestText(); text != "" && req.SuggestBelow > 0 {
+		for _, suggester := range spellingSuggesters(text) {
+			service = service.Suggester(suggester)
+		}
+	}
+
+	if req.Facets {
+		aggregations := map[string]elastic.Aggregation{
+			FacetJournals: elastic.NewTermsAggregation().Field("journal.keyword").Size(facetSize),
+			FacetAuthors:  elastic.NewTermsAggregation().Field("authors.keyword").Size(facetSize),
+			FacetKeywords: elastic.NewTermsAggregation().Field("keywords.keyword").Size(facetSize),
+			FacetYears: elastic.NewDateHistogramAggregation().
+				Field("publication_date").
+				CalendarInterval("year").
+				Format("yyyy").
+				MinDocCount(1),
+		}
+		for name, aggregation := range aggregations {
+			service = service.Aggregation(name, elastic.NewFilterAggregation().
+				Filter(combine(facetFilters, name)).
+				SubAggregation("values", aggregation))
+		}
+	}
+
+	return service
+}
+
+// suggestText is the text spelling corrections are offered for. Queries using
+// the query syntax are left alone, since a correction of their free text alone
+// would drop the rest of the query.
+func (req PublicationRequest) suggestText() string {
+	if req.Query == nil || !req.Query.Plain() {
+		return ""
+	}
+	return req.Query.FreeText()
+}
+
+// FulltextQueryName names the part of a query that searches the text of
+// PDFs, so hits report whether the match came from there
+const FulltextQueryName = "fulltext"
+
+// TextQuery matches free text against the publication fields and, with a
+// lower weight, the text of their PDFs
+func TextQuery(text string) elastic.Query {
+	return elastic.NewBoolQuery().
+		Should(elastic.NewMultiMatchQuery(text,
+			"title^3",     // Boost title relevance
+			"title.cjk^3", // Bigrams match Chinese titles
+			"abstract^2",
+			"abstract.cjk^2",
+			"authors",
+			"keywords",
+			"journal",
+		).Type("best_fields").Fuzziness("AUTO")).
+		Should(fulltextQuery(text, false).Boost(0.5)).
+		MinimumNumberShouldMatch(1)
+}
+
+// fulltextQuery matches text or a phrase against the text of PDFs. Bodies
+// are long, so words are matched exactly rather than fuzzily.
+func fulltextQuery(text string, phrase bool) *elastic.MultiMatchQuery {
+	query := elastic.NewMultiMatchQuery(text, "fulltext", "fulltext.cjk").QueryName(FulltextQueryName)
+	if phrase {
+		return query.Type("phrase")
+	}
+	return query.Type("best_fields").Operator("and")
+}
+
+// ParsePublicationResult decodes hits, facets and suggestions of a publication search
+func ParsePublicationResult(result *elastic.SearchResult, req PublicationRequest) PublicationResult {
+	publications := []PublicationHit{}
+	for _, hit := range result.Hits.Hits {
+		var publication models.PublicationSearch
+		if err := json.Unmarshal(hit.Source, &publication); err != nil {
+			continue
+		}
+
+		highlight := mergeHighlight(hit.Highlight)
+		publications = append(publications, PublicationHit{
+			PublicationSearch: publication,
+			Score:             hit.Score,
+			Highlight:         highlight,
+			Snippet:           snippet(publication.Abstract, highlight["abstract"], req.FragmentSize),
+			MatchedFulltext:   slices.Contains(hit.MatchedQueries, FulltextQueryName),
+		})
+	}
+
+	parsed := PublicationResult{
+		Publications: publications,
+		Total:        result.TotalHits(),
+	}
+
+	if text := req.suggestText(); text != "" && parsed.Total < int64(req.SuggestBelow) {
+		parsed.DidYouMean = parseDidYouMean(result.Suggest, text)
+	}
+
+	if len(result.Aggregations) > 0 {
+		parsed.Facets = make(map[string][]FacetBucket)
+		for _, name := range []string{FacetJournals, FacetAuthors, FacetKeywords} {
+			parsed.Facets[name] = termsBuckets(result.Aggregations, name)
+		}
+		parsed.Facets[FacetYears] = yearBuckets(result.Aggregations)
+	}
+
+	return parsed
+}
+
+func termsBuckets(aggregations elastic.Aggregations, name string) []FacetBucket {
+	buckets := []FacetBucket{}
+
+	filtered, ok := aggregations.Filter(name)
+	if !ok {
+		return buckets
+	}
+	terms, ok := filtered.Terms("values")
+	if !ok {
+		return buckets
+	}
+
+	for _, bucket := range terms.Buckets {
+		value, _ := bucket.Key.(string)
+		buckets = append(buckets, FacetBucket{Value: value, Count: bucket.DocCount})
+	}
+	return buckets
+}
+
+func yearBuckets(aggregations elastic.Aggregations) []FacetBucket {
+	buckets := []FacetBucket{}
+
+	filtered, ok := aggregations.Filter(FacetYears)
+	if !ok {
+		return buckets
+	}
+	histogram, ok := filtered.DateHistogram("values")
+	if !ok {
+		return buckets
+	}
+
+	for _, bucket := range histogram.Buckets {
+		value := strconv.FormatFloat(bucket.Key, 'f', 0, 64)
+		if bucket.KeyAsString != nil {
+			value = *bucket.KeyAsString
+		}
+		buckets = append(buckets, FacetBucket{Value: value, Count: bucket.DocCount})
+	}
+	return buckets
+}
+
+// facetFilter is a filter on a faceted field, tagged with its facet
+type facetFilter struct {
+	facet string
+	query elastic.Query
+}
+
+// facetFilters returns the filters on faceted fields
+func (f Filters) facetFilters() []facetFilter {
+	var filters []facetFilter
+
+	if len(f.Journals) > 0 {
+		filters = append(filters, facetFilter{FacetJournals, anyValue("journal.keyword", f.Journals)})
+	}
+	if len(f.Authors) > 0 {
+		filters = append(filters, facetFilter{FacetAuthors, anyValue("authors.keyword", f.Authors)})
+	}
+	if len(f.Keywords) > 0 {
+		filters = append(filters, facetFilter{FacetKeywords, anyValue("keywords.keyword", f.Keywords)})
+	}
+
+	if f.YearFrom != 0 || f.YearTo != 0 || f.FromDate != "" || f.ToDate != "" {
+		dates := elastic.NewBoolQuery()
+		if f.YearFrom != 0 {
+			dates.Filter(elastic.NewRangeQuery("publication_date").Gte(strconv.Itoa(f.YearFrom)).Format("yyyy"))
+		}
+		if f.YearTo != 0 {
+			// Up to the start of the next year, so the whole of YearTo is included
+			dates.Filter(elastic.NewRangeQuery("publication_date").Lt(strconv.Itoa(f.YearTo + 1)).Format("yyyy"))
+		}
+		if f.FromDate != "" {
+			dates.Filter(elastic.NewRangeQuery("publication_date").Gte(f.FromDate).Format("yyyy-MM-dd"))
+		}
+		if f.ToDate != "" {
+			dates.Filter(elastic.NewRangeQuery("publication_date").Lte(f.ToDate).Format("yyyy-MM-dd"))
+		}
+		filters = append(filters, facetFilter{FacetYears, dates})
+	}
+
+	return filters
+}
+
+// plainFilters returns the filters on fields without a facet
+func (f Filters) plainFilters() []elastic.Query {
+	var filters []elastic.Query
+
+	if f.JournalText != "" {
+		filters = append(filters, elastic.NewMatchPhraseQuery("journal", f.JournalText))
+	}
+
+	if f.MinCitations != nil || f.MaxCitations != nil {
+		citations := elastic.NewRangeQuery("citation_count")
+		if f.MinCitations != nil {
+			citations.Gte(*f.MinCitations)
+		}
+		if f.MaxCitations != nil {
+			citations.Lte(*f.MaxCitations)
+		}
+		filters = append(filters, citations)
+	}
+
+	if f.HasPDF != nil {
+		filters = append(filters, elastic.NewTermQuery("has_pdf", *f.HasPDF))
+	}
+
+	return filters
+}
+
+// combine ANDs the filters, leaving out the one belonging to the excluded facet
+func combine(filters []facetFilter, exclude string) elastic.Query {
+	query := elastic.NewBoolQuery()
+	for _, filter := range filters {
+		if filter.facet != exclude {
+			query.Filter(filter.query)
+		}
+	}
+	return query
+}
+
+// anyValue matches documents where the keyword field equals any of the values,
+// so a selected facet bucket matches exactly the publications it counted
+func anyValue(field string, values []string) elastic.Query {
+	terms := make([]interface{}, len(values))
+	for i, value := range values {
+		terms[i] = value
+	}
+	return elastic.NewTermsQuery(field, terms...)
+}
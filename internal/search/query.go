@@ -0,0 +1,527 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/olivere/elastic/v7"
+)
+
+// Query is a parsed search query. The language is a superset of plain text:
+//
+//	graph neural networks                 free text, ranked like before
+//	"graph neural"                        phrase
+//	author:Smith  journal:"Nature"        field-scoped terms and phrases
+//	title:  abstract:  kw:  doi:          other fields (keyword: is an alias of kw:)
+//	fulltext:"message passing"            the text of the PDF
+//	year:2019  year:2019..2021            publication year or range (either end may be left open)
+//	a AND b   a OR b   NOT a   -a   ( )   boolean operators, AND binds tighter than OR
+//
+// Terms next to each other without an operator must all match, except that
+// runs of plain words are matched together as free text. A prefix that is not
+// a known field, as in "COVID-19: a review", is treated as text.
+type Query struct {
+	root node
+	// plain is true when the query uses none of the syntax above
+	plain bool
+}
+
+// SyntaxError reports a malformed query. Pos is the 1-based character offset.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// queryFields maps field prefixes to the index fields they search
+var queryFields = map[string][]string{
+	"title":    {"title", "title.cjk"},
+	"abstract": {"abstract", "abstract.cjk"},
+	"author":   {"authors"},
+	"journal":  {"journal"},
+	"kw":       {"keywords"},
+	"keyword":  {"keywords"},
+	"doi":      {"doi"},
+	"fulltext": {"fulltext", "fulltext.cjk"},
+	"year":     {"publication_date"},
+}
+
+// ParseQuery parses a search query
+func ParseQuery(input string) (*Query, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, &SyntaxError{Pos: 1, Msg: "empty query"}
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		if tok.kind == tokenRParen {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: "unmatched ')'"}
+		}
+		return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %s", tok)}
+	}
+
+	plain := true
+	for _, tok := range tokens {
+		if tok.kind != tokenWord && tok.kind != tokenEOF {
+			plain = false
+		}
+	}
+
+	return &Query{root: root, plain: plain}, nil
+}
+
+// Plain reports whether the query is free text without any syntax
+func (q *Query) Plain() bool {
+	return q.plain
+}
+
+// FreeText returns the words and phrases not scoped to a field, for use by
+// the spelling suggesters
+func (q *Query) FreeText() string {
+	var parts []string
+	q.root.freeText(&parts)
+	return strings.Join(parts, " ")
+}
+
+// ESQuery compiles the query to an Elasticsearch query
+func (q *Query) ESQuery() elastic.Query {
+	return q.root.compile()
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenPhrase
+	tokenField // field name followed by ':'
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenPhrase:
+		return fmt.Sprintf("phrase %q", t.text)
+	case tokenField:
+		return fmt.Sprintf("field %q", t.text+":")
+	case tokenLParen:
+		return "'('"
+	case tokenRParen:
+		return "')'"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+func lex(input string) ([]token, error) {
+	runes := []rune(input)
+	var tokens []token
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "(", pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")", pos})
+			i++
+		case r == '-' && (i == 0 || isBoundary(runes[i-1])) && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			// A leading minus negates; inside a word it is part of it
+			tokens = append(tokens, token{tokenNot, "-", pos})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, &SyntaxError{Pos: pos, Msg: "unterminated phrase"}
+			}
+			text := strings.TrimSpace(string(runes[i+1 : end]))
+			if text == "" {
+				return nil, &SyntaxError{Pos: pos, Msg: "empty phrase"}
+			}
+			tokens = append(tokens, token{tokenPhrase, text, pos})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !isWordEnd(runes[end]) && runes[end] != ':' {
+				end++
+			}
+
+			if end < len(runes) && runes[end] == ':' {
+				name := strings.ToLower(string(runes[i:end]))
+				if _, ok := queryFields[name]; ok {
+					if end+1 == len(runes) || unicode.IsSpace(runes[end+1]) {
+						return nil, &SyntaxError{Pos: end + 2, Msg: fmt.Sprintf("expected a value after %q", name+":")}
+					}
+					tokens = append(tokens, token{tokenField, name, pos})
+					i = end + 1
+					continue
+				}
+				// Not a field, e.g. "COVID-19:"; the colon is part of the word
+				for end < len(runes) && !isWordEnd(runes[end]) {
+					end++
+				}
+			}
+			word := string(runes[i:end])
+
+			switch word {
+			case "AND", "&&":
+				tokens = append(tokens, token{tokenAnd, word, pos})
+			case "OR", "||":
+				tokens = append(tokens, token{tokenOr, word, pos})
+			case "NOT":
+				tokens = append(tokens, token{tokenNot, word, pos})
+			default:
+				tokens = append(tokens, token{tokenWord, word, pos})
+			}
+			i = end
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes) + 1}), nil
+}
+
+func isWordEnd(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"'
+}
+
+func isBoundary(r rune) bool {
+	return unicode.IsSpace(r) || r == '('
+}
+
+// Parser
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokenEOF {
+		p.i++
+	}
+	return tok
+}
+
+// parseOr parses: and ("OR" and)*
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	children := []node{left}
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+
+	if len(children) == 1 {
+		return left, nil
+	}
+	return &orNode{children: children}, nil
+}
+
+// parseAnd parses: unary (["AND"] unary)*
+func (p *parser) parseAnd() (node, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	group := &andNode{children: []node{first}}
+	for {
+		tok := p.peek()
+		switch tok.kind {
+		case tokenAnd:
+			p.next()
+			child, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			group.children = append(group.children, child)
+			group.explicit = true
+		case tokenWord, tokenPhrase, tokenField, tokenNot, tokenLParen:
+			child, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			group.children = append(group.children, child)
+		default:
+			if len(group.children) == 1 {
+				return first, nil
+			}
+			return group, nil
+		}
+	}
+}
+
+// parseUnary parses: ("NOT" | "-") unary | primary
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().kind == tokenNot {
+		p.next()
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{child: child}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses: "(" or ")" | field value | phrase | word
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: "unmatched '('"}
+		}
+		return inner, nil
+	case tokenWord:
+		return &textNode{text: tok.text}, nil
+	case tokenPhrase:
+		return &textNode{text: tok.text, phrase: true}, nil
+	case tokenField:
+		value := p.next()
+		if value.kind != tokenWord && value.kind != tokenPhrase {
+			return nil, &SyntaxError{Pos: value.pos, Msg: fmt.Sprintf("expected a value after %q, got %s", tok.text+":", value)}
+		}
+		if tok.text == "year" {
+			return parseYear(value)
+		}
+		return &fieldNode{field: tok.text, text: value.text, phrase: value.kind == tokenPhrase}, nil
+	case tokenEOF:
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "unexpected end of query"}
+	}
+
+	return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %s", tok)}
+}
+
+// parseYear parses 2019, 2019..2021, 2019.. and ..2021
+func parseYear(value token) (node, error) {
+	invalid := &SyntaxError{Pos: value.pos, Msg: fmt.Sprintf("invalid year %q; use 2019, 2019..2021, 2019.. or ..2021", value.text)}
+
+	year := func(s string) (int, error) {
+		if s == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 9999 {
+			return 0, invalid
+		}
+		return n, nil
+	}
+
+	from, to, isRange := strings.Cut(value.text, "..")
+	if !isRange {
+		to = from
+	}
+	if from == "" && to == "" {
+		return nil, invalid
+	}
+
+	start, err := year(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := year(to)
+	if err != nil {
+		return nil, err
+	}
+	if start != 0 && end != 0 && start > end {
+		return nil, &SyntaxError{Pos: value.pos, Msg: fmt.Sprintf("year range %q ends before it starts", value.text)}
+	}
+
+	return &yearNode{from: start, to: end}, nil
+}
+
+// Syntax tree
+
+type node interface {
+	compile() elastic.Query
+	freeText(parts *[]string)
+}
+
+type orNode struct {
+	children []node
+}
+
+func (n *orNode) compile() elastic.Query {
+	query := elastic.NewBoolQuery().MinimumNumberShouldMatch(1)
+	for _, child := range n.children {
+		query.Should(child.compile())
+	}
+	return query
+}
+
+func (n *orNode) freeText(parts *[]string) {
+	for _, child := range n.children {
+		child.freeText(parts)
+	}
+}
+
+type andNode struct {
+	children []node
+	// explicit is set when AND was written; then every word must match on its own
+	explicit bool
+}
+
+func (n *andNode) compile() elastic.Query {
+	query := elastic.NewBoolQuery()
+
+	// Adjacent plain words keep the ranking of a free-text search
+	var words []string
+	flush := func() {
+		if len(words) > 0 {
+			query.Must(TextQuery(strings.Join(words, " ")))
+			words = nil
+		}
+	}
+
+	for _, child := range n.children {
+		if text, ok := child.(*textNode); ok && !text.phrase && !n.explicit {
+			words = append(words, text.text)
+			continue
+		}
+		flush()
+		if not, ok := child.(*notNode); ok {
+			query.MustNot(not.child.compile())
+			continue
+		}
+		query.Must(child.compile())
+	}
+	flush()
+
+	return query
+}
+
+func (n *andNode) freeText(parts *[]string) {
+	for _, child := range n.children {
+		child.freeText(parts)
+	}
+}
+
+type notNode struct {
+	child node
+}
+
+func (n *notNode) compile() elastic.Query {
+	return elastic.NewBoolQuery().MustNot(n.child.compile())
+}
+
+// Negated terms are not worth correcting
+func (n *notNode) freeText(parts *[]string) {}
+
+type textNode struct {
+	text   string
+	phrase bool
+}
+
+func (n *textNode) compile() elastic.Query {
+	if n.phrase {
+		return elastic.NewBoolQuery().
+			Should(elastic.NewMultiMatchQuery(n.text,
+				"title^3", "title.cjk^3", "abstract^2", "abstract.cjk^2", "authors", "keywords", "journal",
+			).Type("phrase")).
+			Should(fulltextQuery(n.text, true).Boost(0.5)).
+			MinimumNumberShouldMatch(1)
+	}
+	return TextQuery(n.text)
+}
+
+func (n *textNode) freeText(parts *[]string) {
+	*parts = append(*parts, n.text)
+}
+
+type fieldNode struct {
+	field  string
+	text   string
+	phrase bool
+}
+
+func (n *fieldNode) compile() elastic.Query {
+	fields := queryFields[n.field]
+
+	if n.field == "doi" {
+		// doi is a normalized keyword; match applies the normalizer
+		return elastic.NewMatchQuery("doi", n.text)
+	}
+	if n.field == "fulltext" {
+		return fulltextQuery(n.text, n.phrase)
+	}
+
+	if n.phrase {
+		return elastic.NewMultiMatchQuery(n.text, fields...).Type("phrase")
+	}
+	return elastic.NewMultiMatchQuery(n.text, fields...).Type("best_fields").Operator("and").Fuzziness("AUTO")
+}
+
+func (n *fieldNode) freeText(parts *[]string) {}
+
+type yearNode struct {
+	from, to int
+}
+
+func (n *yearNode) compile() elastic.Query {
+	query := elastic.NewRangeQuery("publication_date").Format("yyyy")
+	if n.from != 0 {
+		query.Gte(strconv.Itoa(n.from))
+	}
+	if n.to != 0 {
+		// An lte year would only reach January 1st, as Elasticsearch fills in
+		// missing date parts; stop before the next year instead
+		query.Lt(strconv.Itoa(n.to + 1))
+	}
+	return query
+}
+
+func (n *yearNode) freeText(parts *[]string) {}
@@ -0,0 +1,223 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// describe renders a syntax tree compactly: implicit AND as (and ...),
+// written AND as (AND ...), phrases quoted and years as from..to
+func describe(n node) string {
+	switch n := n.(type) {
+	case *orNode:
+		return "(OR " + describeAll(n.children) + ")"
+	case *andNode:
+		if n.explicit {
+			return "(AND " + describeAll(n.children) + ")"
+		}
+		return "(and " + describeAll(n.children) + ")"
+	case *notNode:
+		return "(NOT " + describe(n.child) + ")"
+	case *textNode:
+		if n.phrase {
+			return fmt.Sprintf("%q", n.text)
+		}
+		return n.text
+	case *fieldNode:
+		if n.phrase {
+			return fmt.Sprintf("%s:%q", n.field, n.text)
+		}
+		return n.field + ":" + n.text
+	case *yearNode:
+		return fmt.Sprintf("year:%d..%d", n.from, n.to)
+	}
+	return fmt.Sprintf("%T", n)
+}
+
+func describeAll(nodes []node) string {
+	parts := make([]string, len(nodes))
+	for i, n := range nodes {
+		parts[i] = describe(n)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		plain bool
+	}{
+		// Free text and phrases
+		{"graph", "graph", true},
+		{"graph neural networks", "(and graph neural networks)", true},
+		{`"graph neural"`, `"graph neural"`, false},
+		{`deep "graph neural" nets`, `(and deep "graph neural" nets)`, false},
+
+		// Boolean operators; AND binds tighter than OR
+		{"a AND b", "(AND a b)", false},
+		{"a OR b", "(OR a b)", false},
+		{"a OR b c", "(OR a (and b c))", false},
+		{"a AND b OR c", "(OR (AND a b) c)", false},
+		{"a OR b AND c", "(OR a (AND b c))", false},
+		{"a && b || c", "(OR (AND a b) c)", false},
+		{"(a OR b) c", "(and (OR a b) c)", false},
+		{"a (b OR c)", "(and a (OR b c))", false},
+		{"NOT a b", "(and (NOT a) b)", false},
+		{"NOT (a OR b)", "(NOT (OR a b))", false},
+		{"and or not", "(and and or not)", true},
+
+		// Minus negates at the start of a term only
+		{"-a b", "(and (NOT a) b)", false},
+		{"graph -survey", "(and graph (NOT survey))", false},
+		{`-"deep learning"`, `(NOT "deep learning")`, false},
+		{"COVID-19 vaccines", "(and COVID-19 vaccines)", true},
+		{"a - b", "(and a - b)", true},
+
+		// Field prefixes
+		{"author:Smith", "author:Smith", false},
+		{"Author:Smith", "author:Smith", false},
+		{`journal:"Nature Physics"`, `journal:"Nature Physics"`, false},
+		{"doi:10.1000/ABC", "doi:10.1000/ABC", false},
+		{"kw:graphs", "kw:graphs", false},
+		{"keyword:graphs", "keyword:graphs", false},
+		{"title:graphs", "title:graphs", false},
+		{"abstract:graphs", "abstract:graphs", false},
+		{`fulltext:"message passing"`, `fulltext:"message passing"`, false},
+		{"graphs author:Smith", "(and graphs author:Smith)", false},
+		{"author:Smith OR author:Jones", "(OR author:Smith author:Jones)", false},
+		{"-author:Smith", "(NOT author:Smith)", false},
+
+		// Years
+		{"year:2019", "year:2019..2019", false},
+		{"year:2019..2021", "year:2019..2021", false},
+		{"year:2019..", "year:2019..0", false},
+		{"year:..2021", "year:0..2021", false},
+
+		// Colons that do not follow a field are text
+		{"COVID-19: review", "(and COVID-19: review)", true},
+		{"COVID-19:review", "COVID-19:review", true},
+		{"note: see http://example.org", "(and note: see http://example.org)", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			query, err := ParseQuery(tt.input)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) failed: %v", tt.input, err)
+			}
+			if got := describe(query.root); got != tt.want {
+				t.Errorf("ParseQuery(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+			if query.Plain() != tt.plain {
+				t.Errorf("Plain() = %v, want %v", query.Plain(), tt.plain)
+			}
+		})
+	}
+}
+
+func TestParseQuerySyntaxErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		pos   int
+		msg   string
+	}{
+		{"empty query", "   ", 1, "empty query"},
+		{"unterminated quote", `graph "neural`, 7, "unterminated phrase"},
+		{"unterminated quote after CJK", `图 "网络`, 3, "unterminated phrase"},
+		{"empty phrase", `graph "  "`, 7, "empty phrase"},
+		{"unmatched open paren", "(a OR b", 1, "unmatched '('"},
+		{"unmatched inner open paren", "a (b (c)", 3, "unmatched '('"},
+		{"unmatched close paren", "a OR b)", 7, "unmatched ')'"},
+		{"trailing AND", "graph AND", 10, "unexpected end of query"},
+		{"trailing OR", "graph OR", 9, "unexpected end of query"},
+		{"trailing NOT", "graph NOT", 10, "unexpected end of query"},
+		{"leading OR", "OR graph", 1, `unexpected "OR"`},
+		{"empty parens", "()", 2, "unexpected ')'"},
+		{"field at the end", "author:", 8, `expected a value after "author:"`},
+		{"field before a space", "author: Smith", 8, `expected a value after "author:"`},
+		{"field before a paren", "author:(Smith)", 8, `expected a value after "author:", got '('`},
+		{"invalid year", "year:recent", 6, `invalid year "recent"`},
+		{"open year range", "year:..", 6, `invalid year ".."`},
+		{"year out of range", "year:2019..99999", 6, `invalid year "2019..99999"`},
+		{"reversed year range", "graphs year:2021..2019", 13, `year range "2021..2019" ends before it starts`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseQuery(tt.input)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("ParseQuery(%q) error = %v, want a SyntaxError", tt.input, err)
+			}
+			if syntaxErr.Pos != tt.pos {
+				t.Errorf("Pos = %d, want %d (%s)", syntaxErr.Pos, tt.pos, syntaxErr.Msg)
+			}
+			if !strings.HasPrefix(syntaxErr.Msg, tt.msg) {
+				t.Errorf("Msg = %q, want it to start with %q", syntaxErr.Msg, tt.msg)
+			}
+		})
+	}
+}
+
+func TestYearRangeIncludesWholeYears(t *testing.T) {
+	tests := []struct {
+		input string
+		want  map[string]interface{}
+	}{
+		{"year:2019..2021", map[string]interface{}{"from": "2019", "include_lower": true, "to": "2022", "include_upper": false}},
+		{"year:2020", map[string]interface{}{"from": "2020", "include_lower": true, "to": "2021", "include_upper": false}},
+		{"year:..2021", map[string]interface{}{"from": nil, "to": "2022", "include_upper": false}},
+		{"year:2019..", map[string]interface{}{"from": "2019", "include_lower": true, "to": nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			query, err := ParseQuery(tt.input)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) failed: %v", tt.input, err)
+			}
+			source, err := query.ESQuery().Source()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(source)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var decoded struct {
+				Range map[string]map[string]interface{} `json:"range"`
+			}
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			date := decoded.Range["publication_date"]
+			if date == nil {
+				t.Fatalf("ESQuery() = %s, want a publication_date range", data)
+			}
+			if date["format"] != "yyyy" {
+				t.Errorf("format = %v, want yyyy", date["format"])
+			}
+			for key, want := range tt.want {
+				if date[key] != want {
+					t.Errorf("%s = %v, want %v in %s", key, date[key], want, data)
+				}
+			}
+		})
+	}
+}
+
+func TestFreeText(t *testing.T) {
+	query, err := ParseQuery(`graph -survey author:Smith "neural nets" OR (deep year:2020)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := query.FreeText(), "graph neural nets deep"; got != want {
+		t.Errorf("FreeText() = %q, want %q", got, want)
+	}
+}
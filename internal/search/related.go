@@ -0,0 +1,333 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"freescholar-backend/internal/indexer"
+	"freescholar-backend/internal/models"
+	"freescholar-backend/pkg/elasticsearch"
+
+	"github.com/olivere/elastic/v7"
+	"gorm.io/gorm"
+)
+
+// Reason types of a related publication
+const (
+	ReasonSimilarContent = "similar_content"
+	ReasonSharedAuthors  = "shared_authors"
+	ReasonSharedKeywords = "shared_keywords"
+	ReasonCitation       = "citation"
+	ReasonSharedCitation = "shared_references"
+)
+
+// Weights of the signals in the combined score. Content similarity is
+// normalized to the best match, overlaps to the size of the source's lists.
+const (
+	weightContent    = 0.4
+	weightAuthors    = 0.2
+	weightKeywords   = 0.15
+	weightReferences = 0.15
+	weightCitation   = 0.1
+)
+
+// candidatesPerResult is how many candidates each signal contributes per requested result
+const candidatesPerResult = 3
+
+// Reason explains why a publication is recommended
+type Reason struct {
+	Type string `json:"type"`
+	// Values are the shared authors or keywords
+	Values      []string `json:"values,omitempty"`
+	Description string   `json:"description"`
+}
+
+// RelatedPublication is a recommendation with its combined score
+type RelatedPublication struct {
+	models.PublicationSearch
+	Score   float64  `json:"score"`
+	Reasons []Reason `json:"reasons"`
+}
+
+type relatedCandidate struct {
+	content    float64
+	authors    []string
+	keywords   []string
+	references int
+	// cites and citedBy record a direct citation between the two publications
+	cites   bool
+	citedBy bool
+}
+
+// Related ranks publications related to the given one by combining
+// more_like_this on its text with co-author, keyword and reference overlap
+// and direct citations from MySQL
+func Related(ctx context.Context, db *gorm.DB, esClient *elasticsearch.Client, publicationID uint, limit int) ([]RelatedPublication, error) {
+	candidates := make(map[uint]*relatedCandidate)
+	candidate := func(id uint) *relatedCandidate {
+		if candidates[id] == nil {
+			candidates[id] = &relatedCandidate{}
+		}
+		return candidates[id]
+	}
+
+	// Content similarity from Elasticsearch
+	mlt := elastic.NewMoreLikeThisQuery().
+		Field("title", "abstract", "keywords").
+		LikeItems(elastic.NewMoreLikeThisQueryItem().Index(elasticsearch.PublicationsAlias).Id(strconv.Itoa(int(publicationID)))).
+		MinTermFreq(1).
+		MinDocFreq(2).
+		MaxQueryTerms(25)
+
+	result, err := esClient.Search().
+		Index(elasticsearch.PublicationsAlias).
+		Query(mlt).
+		FetchSource(false).
+		Size(limit * candidatesPerResult).
+		Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("more_like_this failed: %w", err)
+	}
+
+	var maxScore float64
+	if result.Hits.MaxScore != nil {
+		maxScore = *result.Hits.MaxScore
+	}
+	for _, hit := range result.Hits.Hits {
+		id, err := strconv.ParseUint(hit.Id, 10, 64)
+		if err != nil || hit.Score == nil || maxScore == 0 {
+			continue
+		}
+		candidate(uint(id)).content = *hit.Score / maxScore
+	}
+
+	// Co-author and keyword overlap with the live publications sharing the most
+	authorNames, err := sharedNames(db, publicationID, "publication_authors", "author_id", "authors", true, limit*candidatesPerResult)
+	if err != nil {
+		return nil, err
+	}
+	for id, names := range authorNames {
+		candidate(id).authors = names
+	}
+
+	keywordNames, err := sharedNames(db, publicationID, "publication_keywords", "keyword_id", "keywords", false, limit*candidatesPerResult)
+	if err != nil {
+		return nil, err
+	}
+	for id, names := range keywordNames {
+		candidate(id).keywords = names
+	}
+
+	// Bibliographic coupling: publications citing the same works
+	var referenceRows []struct {
+		CitingID uint
+		Shared   int
+	}
+	err = db.Table("citations AS source").
+		Select("other.citing_id, COUNT(DISTINCT other.cited_id) AS shared").
+		Joins("JOIN citations AS other ON other.cited_id = source.cited_id AND other.citing_id <> source.citing_id AND other.deleted_at IS NULL").
+		Joins("JOIN publications ON publications.id = other.citing_id AND publications.deleted_at IS NULL").
+		Where("source.citing_id = ? AND source.cited_id IS NOT NULL AND source.deleted_at IS NULL", publicationID).
+		Group("other.citing_id").
+		Order("shared DESC, other.citing_id").
+		Limit(limit * candidatesPerResult).
+		Scan(&referenceRows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range referenceRows {
+		candidate(row.CitingID).references = row.Shared
+	}
+
+	// Direct citations in either direction
+	var citations []models.Citation
+	err = db.Where("(citing_id = ? AND cited_id IS NOT NULL) OR cited_id = ?", publicationID, publicationID).
+		Find(&citations).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, citation := range citations {
+		if citation.CitingID == publicationID {
+			candidate(*citation.CitedID).cites = true
+		} else {
+			candidate(citation.CitingID).citedBy = true
+		}
+	}
+
+	if len(candidates) == 0 {
+		return []RelatedPublication{}, nil
+	}
+
+	var sourceAuthors, sourceKeywords, sourceReferences int64
+	db.Table("publication_authors").Where("publication_id = ? AND deleted_at IS NULL", publicationID).Count(&sourceAuthors)
+	db.Table("publication_keywords").Where("publication_id = ?", publicationID).Count(&sourceKeywords)
+	db.Model(&models.Citation{}).Where("citing_id = ? AND cited_id IS NOT NULL", publicationID).Count(&sourceReferences)
+
+	// Rank, then load the best candidates that still exist
+	ranked := make([]RelatedPublication, 0, len(candidates))
+	for id, c := range candidates {
+		ranked = append(ranked, RelatedPublication{
+			PublicationSearch: models.PublicationSearch{ID: id},
+			Score:             c.score(sourceAuthors, sourceKeywords, sourceReferences),
+			Reasons:           c.reasons(),
+		})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].ID < ranked[j].ID
+	})
+	if len(ranked) > limit*candidatesPerResult {
+		ranked = ranked[:limit*candidatesPerResult]
+	}
+
+	ids := make([]uint, len(ranked))
+	for i, related := range ranked {
+		ids[i] = related.ID
+	}
+
+	var publications []models.Publication
+	if err := db.Where("id IN ?", ids).Find(&publications).Error; err != nil {
+		return nil, err
+	}
+	docs, err := indexer.LoadPublicationDocuments(db, publications)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.PublicationSearch, len(docs))
+	for _, doc := range docs {
+		byID[doc.ID] = doc
+	}
+
+	related := []RelatedPublication{}
+	for _, r := range ranked {
+		doc, ok := byID[r.ID]
+		if !ok {
+			continue
+		}
+		r.PublicationSearch = doc
+		related = append(related, r)
+		if len(related) == limit {
+			break
+		}
+	}
+
+	return related, nil
+}
+
+// sharedNames finds the live publications sharing the most entries of a link
+// table, such as publication_authors, with the given publication and returns
+// the names of the shared entries by publication. Counting and limiting happen
+// in SQL, so prolific authors and common keywords do not load every publication
+// they appear on.
+func sharedNames(db *gorm.DB, publicationID uint, table, column, names string, softDelete bool, limit int) (map[uint][]string, error) {
+	shared := func() *gorm.DB {
+		otherJoin := fmt.Sprintf("JOIN %s AS other ON other.%s = source.%s AND other.publication_id <> source.publication_id", table, column, column)
+		sourceWhere := "source.publication_id = ?"
+		if softDelete {
+			otherJoin += " AND other.deleted_at IS NULL"
+			sourceWhere += " AND source.deleted_at IS NULL"
+		}
+		return db.Table(table+" AS source").
+			Joins(otherJoin).
+			Joins(fmt.Sprintf("JOIN %s ON %s.id = source.%s AND %s.deleted_at IS NULL", names, names, column, names)).
+			Joins("JOIN publications ON publications.id = other.publication_id AND publications.deleted_at IS NULL").
+			Where(sourceWhere, publicationID)
+	}
+
+	var ids []uint
+	err := shared().
+		Select("other.publication_id").
+		Group("other.publication_id").
+		Order(fmt.Sprintf("COUNT(DISTINCT other.%s) DESC, other.publication_id", column)).
+		Limit(limit).
+		Pluck("other.publication_id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var rows []struct {
+		PublicationID uint
+		Name          string
+	}
+	err = shared().
+		Select(fmt.Sprintf("DISTINCT other.publication_id, %s.name", names)).
+		Where("other.publication_id IN ?", ids).
+		Order(fmt.Sprintf("other.publication_id, %s.name", names)).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byPublication := make(map[uint][]string, len(ids))
+	for _, row := range rows {
+		byPublication[row.PublicationID] = append(byPublication[row.PublicationID], row.Name)
+	}
+	return byPublication, nil
+}
+
+func (c *relatedCandidate) score(sourceAuthors, sourceKeywords, sourceReferences int64) float64 {
+	score := weightContent * c.content
+	if sourceReferences > 0 {
+		score += weightReferences * float64(c.references) / float64(sourceReferences)
+	}
+	if c.cites || c.citedBy {
+		score += weightCitation
+	}
+	if sourceAuthors > 0 {
+		score += weightAuthors * float64(len(c.authors)) / float64(sourceAuthors)
+	}
+	if sourceKeywords > 0 {
+		score += weightKeywords * float64(len(c.keywords)) / float64(sourceKeywords)
+	}
+	return score
+}
+
+func (c *relatedCandidate) reasons() []Reason {
+	var reasons []Reason
+
+	if c.cites {
+		reasons = append(reasons, Reason{Type: ReasonCitation, Description: "Cited by this publication"})
+	}
+	if c.citedBy {
+		reasons = append(reasons, Reason{Type: ReasonCitation, Description: "Cites this publication"})
+	}
+	if c.references > 0 {
+		reasons = append(reasons, Reason{
+			Type:        ReasonSharedCitation,
+			Description: fmt.Sprintf("Cites %d of the same %s", c.references, plural(c.references, "publication", "publications")),
+		})
+	}
+	if len(c.authors) > 0 {
+		reasons = append(reasons, Reason{
+			Type:        ReasonSharedAuthors,
+			Values:      c.authors,
+			Description: fmt.Sprintf("Shares %d %s", len(c.authors), plural(len(c.authors), "author", "authors")),
+		})
+	}
+	if len(c.keywords) > 0 {
+		reasons = append(reasons, Reason{
+			Type:        ReasonSharedKeywords,
+			Values:      c.keywords,
+			Description: fmt.Sprintf("Shares %d %s", len(c.keywords), plural(len(c.keywords), "keyword", "keywords")),
+		})
+	}
+	if c.content > 0 {
+		reasons = append(reasons, Reason{
+			Type:        ReasonSimilarContent,
+			Description: "Similar title and abstract",
+		})
+	}
+
+	return reasons
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
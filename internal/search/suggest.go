@@ -0,0 +1,256 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"freescholar-backend/pkg/elasticsearch"
+
+	"github.com/olivere/elastic/v7"
+)
+
+// Suggestion kinds served by Suggest
+const (
+	SuggestTitles   = "titles"
+	SuggestAuthors  = "authors"
+	SuggestJournals = "journals"
+	SuggestKeywords = "keywords"
+)
+
+// SuggestKinds lists every suggestion kind
+var SuggestKinds = []string{SuggestTitles, SuggestAuthors, SuggestJournals, SuggestKeywords}
+
+// Suggestion is one autocomplete entry. ID is set for titles and authors,
+// Count (the number of publications) for journals and keywords.
+type Suggestion struct {
+	ID    uint   `json:"id,omitempty"`
+	Text  string `json:"text"`
+	Count int64  `json:"count,omitempty"`
+}
+
+// DidYouMean is a spelling correction of a query that found few results
+type DidYouMean struct {
+	Text string `json:"text"`
+	// Highlighted marks the corrected words with HighlightPreTag/HighlightPostTag
+	Highlighted string `json:"highlighted"`
+}
+
+// Suggester names attached to searches with spelling suggestions enabled
+const (
+	phraseSuggester = "did_you_mean_phrase"
+	termSuggester   = "did_you_mean_terms"
+)
+
+// bucketsPerSuggestion over-fetches facet values, since buckets of
+// documents matching the prefix also include values that do not
+const bucketsPerSuggestion = 5
+
+// Suggest returns completions of prefix for each of the requested kinds,
+// using one multi-search request
+func Suggest(ctx context.Context, esClient *elasticsearch.Client, prefix string, kinds []string, limit int) (map[string][]Suggestion, error) {
+	multi := esClient.MultiSearch()
+	for _, kind := range kinds {
+		request, err := suggestRequest(kind, prefix, limit)
+		if err != nil {
+			return nil, err
+		}
+		multi.Add(request)
+	}
+
+	result, err := multi.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Responses) != len(kinds) {
+		return nil, fmt.Errorf("expected %d responses, got %d", len(kinds), len(result.Responses))
+	}
+
+	suggestions := make(map[string][]Suggestion, len(kinds))
+	for i, kind := range kinds {
+		response := result.Responses[i]
+		if response.Error != nil {
+			return nil, fmt.Errorf("%s suggestions failed: %s", kind, response.Error.Reason)
+		}
+
+		switch kind {
+		case SuggestTitles:
+			suggestions[kind] = documentSuggestions(response, "title")
+		case SuggestAuthors:
+			suggestions[kind] = documentSuggestions(response, "name")
+		default:
+			suggestions[kind] = bucketSuggestions(response, prefix, limit)
+		}
+	}
+
+	return suggestions, nil
+}
+
+func suggestRequest(kind, prefix string, limit int) (*elastic.SearchRequest, error) {
+	match := func(field string) elastic.Query {
+		return elastic.NewMatchQuery(field, prefix).Operator("and")
+	}
+
+	switch kind {
+	case SuggestTitles:
+		source := elastic.NewSearchSource().
+			Query(match("title.autocomplete")).
+			FetchSourceIncludeExclude([]string{"id", "title"}, nil).
+			Size(limit)
+		return elastic.NewSearchRequest().Index(elasticsearch.PublicationsAlias).SearchSource(source), nil
+	case SuggestAuthors:
+		source := elastic.NewSearchSource().
+			Query(match("name.autocomplete")).
+			FetchSourceIncludeExclude([]string{"id", "name"}, nil).
+			Size(limit)
+		return elastic.NewSearchRequest().Index(elasticsearch.AuthorsAlias).SearchSource(source), nil
+	case SuggestJournals, SuggestKeywords:
+		field := "journal"
+		if kind == SuggestKeywords {
+			field = "keywords"
+		}
+		source := elastic.NewSearchSource().
+			Query(match(field+".autocomplete")).
+			Size(0).
+			Aggregation("values", elastic.NewTermsAggregation().Field(field+".keyword").Size(limit*bucketsPerSuggestion))
+		return elastic.NewSearchRequest().Index(elasticsearch.PublicationsAlias).SearchSource(source), nil
+	}
+
+	return nil, fmt.Errorf("unknown suggestion type %q", kind)
+}
+
+// documentSuggestions reads id and a text field from the hits
+func documentSuggestions(result *elastic.SearchResult, field string) []Suggestion {
+	suggestions := []Suggestion{}
+	if result.Hits == nil {
+		return suggestions
+	}
+
+	for _, hit := range result.Hits.Hits {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(hit.Source, &doc); err != nil {
+			continue
+		}
+		text, _ := doc[field].(string)
+		id, _ := strconv.ParseUint(hit.Id, 10, 64)
+		suggestions = append(suggestions, Suggestion{ID: uint(id), Text: text})
+	}
+
+	return suggestions
+}
+
+// bucketSuggestions keeps the aggregated values that themselves match the prefix
+func bucketSuggestions(result *elastic.SearchResult, prefix string, limit int) []Suggestion {
+	suggestions := []Suggestion{}
+
+	terms, ok := result.Aggregations.Terms("values")
+	if !ok {
+		return suggestions
+	}
+
+	for _, bucket := range terms.Buckets {
+		value, _ := bucket.Key.(string)
+		if !matchesPrefix(value, prefix) {
+			continue
+		}
+		suggestions = append(suggestions, Suggestion{Text: value, Count: bucket.DocCount})
+		if len(suggestions) == limit {
+			break
+		}
+	}
+
+	return suggestions
+}
+
+// matchesPrefix reports whether every word of prefix starts a word of value
+func matchesPrefix(value, prefix string) bool {
+	words := splitWords(value)
+	for _, want := range splitWords(prefix) {
+		found := false
+		for _, word := range words {
+			if strings.HasPrefix(word, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func splitWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// spellingSuggesters correct the query against the vocabulary of titles:
+// the phrase suggester for whole-query corrections, the term suggester for
+// single words it has no candidates for
+func spellingSuggesters(text string) []elastic.Suggester {
+	return []elastic.Suggester{
+		elastic.NewPhraseSuggester(phraseSuggester).
+			Text(text).
+			Field("title.shingle").
+			Size(1).
+			GramSize(3).
+			MaxErrors(2).
+			Highlight(HighlightPreTag, HighlightPostTag).
+			CandidateGenerator(elastic.NewDirectCandidateGenerator("title").SuggestMode("always").MinWordLength(3)),
+		elastic.NewTermSuggester(termSuggester).
+			Text(text).
+			Field("title").
+			Size(1).
+			SuggestMode("missing").
+			MinWordLength(3),
+	}
+}
+
+// parseDidYouMean picks the phrase correction, falling back to applying the
+// per-word corrections to the original text
+func parseDidYouMean(suggest elastic.SearchSuggest, text string) *DidYouMean {
+	for _, suggestion := range suggest[phraseSuggester] {
+		if len(suggestion.Options) > 0 {
+			option := suggestion.Options[0]
+			highlighted := option.Highlighted
+			if highlighted == "" {
+				highlighted = option.Text
+			}
+			return &DidYouMean{Text: option.Text, Highlighted: highlighted}
+		}
+	}
+
+	// Replace corrected words from the end so earlier offsets stay valid
+	words := append([]elastic.SearchSuggestion(nil), suggest[termSuggester]...)
+	sort.Slice(words, func(i, j int) bool { return words[i].Offset > words[j].Offset })
+
+	runes := []rune(text)
+	corrected := string(runes)
+	highlighted := corrected
+	changed := false
+	for _, word := range words {
+		if len(word.Options) == 0 || word.Offset+word.Length > len(runes) {
+			continue
+		}
+		replacement := word.Options[0].Text
+		corrected = replaceRunes(corrected, word.Offset, word.Length, replacement)
+		highlighted = replaceRunes(highlighted, word.Offset, word.Length, HighlightPreTag+replacement+HighlightPostTag)
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return &DidYouMean{Text: corrected, Highlighted: highlighted}
+}
+
+func replaceRunes(s string, offset, length int, replacement string) string {
+	runes := []rune(s)
+	return string(runes[:offset]) + replacement + string(runes[offset+length:])
+}
@@ -0,0 +1,167 @@
+// Package trash purges deleted publications once their retention period
+// has passed. Until then a deleted publication keeps its authors, keywords,
+// references, revisions and files so it can be restored.
+package trash
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"freescholar-backend/config"
+	"freescholar-backend/internal/indexer"
+	"freescholar-backend/internal/media"
+	"freescholar-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// purgeBatchSize is how many publications one purge run removes per query
+const purgeBatchSize = 100
+
+// Worker purges expired publications from the trash at a fixed interval
+type Worker struct {
+	db        *gorm.DB
+	store     *media.Store
+	retention time.Duration
+	interval  time.Duration
+}
+
+// NewWorker creates a new purge worker
+func NewWorker(db *gorm.DB, store *media.Store, cfg config.TrashConfig) *Worker {
+	w := &Worker{
+		db:        db,
+		store:     store,
+		retention: Retention(cfg),
+		interval:  time.Duration(cfg.PurgeInterval) * time.Minute,
+	}
+
+	if w.interval <= 0 {
+		w.interval = time.Hour
+	}
+
+	return w
+}
+
+// Retention returns how long deleted publications stay in the trash
+func Retention(cfg config.TrashConfig) time.Duration {
+	if cfg.RetentionDays < 1 {
+		return 30 * 24 * time.Hour
+	}
+	return time.Duration(cfg.RetentionDays) * 24 * time.Hour
+}
+
+// Run purges expired publications until the context is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := Purge(ctx, w.db, w.store, time.Now().Add(-w.retention))
+		if err != nil {
+			log.Printf("Trash purge: %v", err)
+		} else if purged > 0 {
+			log.Printf("Trash purge: removed %d publications", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge permanently removes publications deleted before cutoff, together
+// with their associations and stored files no other publication uses, and
+// returns how many were removed
+func Purge(ctx context.Context, db *gorm.DB, store *media.Store, cutoff time.Time) (int, error) {
+	purged := 0
+
+	for {
+		var ids []uint
+		err := db.Unscoped().Model(&models.Publication{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Order("id ASC").
+			Limit(purgeBatchSize).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return purged, err
+		}
+		if len(ids) == 0 {
+			return purged, nil
+		}
+
+		var paths []string
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			paths, err = purgePublications(tx, ids)
+			return err
+		}); err != nil {
+			return purged, err
+		}
+		purged += len(ids)
+
+		// Stored content is shared by checksum, so only remove what nothing refers to any more
+		for _, path := range paths {
+			var users int64
+			if err := db.Unscoped().Model(&models.File{}).Where("file_path = ?", path).Count(&users).Error; err != nil {
+				return purged, err
+			}
+			if users == 0 {
+				if err := store.Remove(ctx, path); err != nil {
+					log.Printf("Trash purge: failed to remove %s: %v", path, err)
+				}
+			}
+		}
+	}
+}
+
+// purgePublications hard-deletes publications and everything linked to
+// them, and returns the stored paths of their files
+func purgePublications(tx *gorm.DB, ids []uint) ([]string, error) {
+	if err := tx.Unscoped().Where("publication_id IN ?", ids).Delete(&models.PublicationAuthor{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Exec("DELETE FROM publication_keywords WHERE publication_id IN ?", ids).Error; err != nil {
+		return nil, err
+	}
+
+	// Their reference lists go; references to them from other publications
+	// are kept as unresolved references
+	if err := tx.Unscoped().Where("citing_id IN ?", ids).Delete(&models.Citation{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Unscoped().Model(&models.Citation{}).Where("cited_id IN ?", ids).Update("cited_id", nil).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Unscoped().Where("publication_id IN ?", ids).Delete(&models.PublicationRevision{}).Error; err != nil {
+		return nil, err
+	}
+	// Redirects from merged duplicates to a purged publication lead nowhere
+	if err := tx.Unscoped().Where("to_id IN ?", ids).Delete(&models.PublicationRedirect{}).Error; err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	if err := tx.Unscoped().Model(&models.File{}).Where("publication_id IN ?", ids).Distinct().Pluck("file_path", &paths).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Unscoped().Where("publication_id IN ?", ids).Delete(&models.File{}).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Unscoped().Where("id IN ?", ids).Delete(&models.Publication{}).Error; err != nil {
+		return nil, err
+	}
+
+	// Trashed publications already left the index; recording the purge lets
+	// a reindex running meanwhile drop them too
+	for _, id := range ids {
+		if err := indexer.DeletePublication(tx, id); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
@@ -5,10 +5,16 @@ import (
 	"fmt"
 	"freescholar-backend/api/routers"
 	"freescholar-backend/config"
+	"freescholar-backend/internal/fulltext"
+	"freescholar-backend/internal/indexer"
+	"freescholar-backend/internal/media"
 	"freescholar-backend/internal/models"
+	"freescholar-backend/internal/trash"
 	"freescholar-backend/pkg/elasticsearch"
+	"freescholar-backend/pkg/mailer"
 	"freescholar-backend/pkg/mysql"
 	"freescholar-backend/pkg/redis"
+	"freescholar-backend/pkg/storage"
 	"log"
 	"net/http"
 	"os"
@@ -20,6 +26,11 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		runReindex(os.Args[2:])
+		return
+	}
+
 	// Load configuration
 	cfg, err := config.LoadConfig("./config.yaml", "./secrets.json")
 	if err != nil {
@@ -58,8 +69,37 @@ func main() {
 		log.Fatalf("Failed to connect to Elasticsearch: %v", err)
 	}
 
+	// Create missing indices and refuse to run against outdated mappings
+	if err := esClient.EnsureIndices(context.Background()); err != nil {
+		log.Fatalf("Failed to set up Elasticsearch indices: %v", err)
+	}
+
+	// Start the worker that keeps Elasticsearch in sync with MySQL
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go indexer.NewWorker(db, esClient, cfg.ES).Run(workerCtx)
+
+	// Set up storage for uploaded files
+	mediaStorage, err := storage.New(cfg.Media)
+	if err != nil {
+		log.Fatalf("Failed to set up media storage: %v", err)
+	}
+	mediaStore := media.NewStore(mediaStorage)
+
+	// Start the workers that extract the text of uploaded PDFs and purge
+	// publications from the trash after their retention period
+	go fulltext.NewWorker(db, mediaStore, cfg.Fulltext).Run(workerCtx)
+	go trash.NewWorker(db, mediaStore, cfg.Trash).Run(workerCtx)
+
+	// Set up mailer; Close drains queued emails before exit
+	mail, err := mailer.New(cfg.Email)
+	if err != nil {
+		log.Fatalf("Failed to set up mailer: %v", err)
+	}
+	defer mail.Close()
+
 	// Set up Gin router with routes
-	router := routers.SetupRouter(cfg, db, redisClient, esClient)
+	router := routers.SetupRouter(cfg, db, redisClient, esClient, mail, mediaStore)
 
 	// Create HTTP server
 	server := &http.Server{
@@ -84,6 +124,9 @@ func main() {
 
 	fmt.Println("Shutting down server...")
 
+	// Stop background workers
+	stopWorkers()
+
 	// Create a deadline for server shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -98,8 +141,12 @@ func main() {
 
 // migrateDB performs database migrations using GORM
 func migrateDB(db *gorm.DB) error {
+	// Accounts created before email verification existed are trusted as verified
+	backfillVerified := db.Migrator().HasTable(&models.User{}) &&
+		!db.Migrator().HasColumn(&models.User{}, "EmailVerifiedAt")
+
 	// Add all models that need to be migrated
-	return db.AutoMigrate(
+	err := db.AutoMigrate(
 		&models.User{},
 		&models.Publication{},
 		&models.Author{},
@@ -110,5 +157,94 @@ func migrateDB(db *gorm.DB) error {
 		&models.SearchHistory{},
 		&models.File{},
 		&models.Serialization{},
+		&models.SearchOutbox{},
+		&models.Citation{},
+		&models.PublicationRedirect{},
+		&models.PublicationRevision{},
 	)
-}
\ No newline at end of file
+	if err != nil {
+		return err
+	}
+
+	// Publications without a DOI hold NULL so they do not collide on the unique index
+	if err := db.Exec("UPDATE publications SET doi = NULL WHERE doi = ''").Error; err != nil {
+		return err
+	}
+
+	if err := normalizeDOIs(db); err != nil {
+		return err
+	}
+
+	if backfillVerified {
+		if err := db.Exec("UPDATE users SET email_verified_at = date_joined WHERE email_verified_at IS NULL").Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// normalizeDOIs rewrites DOIs stored before they were normalized, such as
+// resolver URLs or upper-case DOIs, so the unique index and DOI lookups see
+// them. A DOI whose normalized form another publication already holds is left
+// as it is and reported; such pairs are listed as duplicates to be merged.
+func normalizeDOIs(db *gorm.DB) error {
+	// Normalized DOIs start with "10." and are lower case and trimmed
+	var rows []struct {
+		ID        uint
+		DOI       string
+		DeletedAt gorm.DeletedAt
+	}
+	err := db.Unscoped().Model(&models.Publication{}).
+		Select("id", "doi", "deleted_at").
+		Where("doi NOT LIKE '10.%' OR BINARY doi <> LOWER(doi) OR doi <> TRIM(doi) OR doi REGEXP '[.,;:)}]$'").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	normalized := 0
+	for _, row := range rows {
+		doi := models.NormalizeDOI(row.DOI)
+		if doi == row.DOI {
+			continue
+		}
+
+		var existing models.Publication
+		if db.Unscoped().Select("id").Where("doi = ? AND id <> ?", doi, row.ID).Limit(1).Find(&existing).RowsAffected > 0 {
+			log.Printf("DOI of publication %d (%q) is the DOI of publication %d; merge them to normalize it", row.ID, row.DOI, existing.ID)
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			publication := models.Publication{Model: gorm.Model{ID: row.ID}, DOI: models.DOI(doi)}
+			if err := tx.Unscoped().Model(&publication).UpdateColumn("doi", publication.DOI).Error; err != nil {
+				return err
+			}
+			// Trashed publications are left out of citations and the index
+			if row.DeletedAt.Valid {
+				return nil
+			}
+			// References waiting for the normalized DOI can now be linked
+			linked, err := models.ResolveCitations(tx, publication)
+			if err != nil {
+				return err
+			}
+			if linked > 0 {
+				if err := models.RecountCitations(tx, row.ID); err != nil {
+					return err
+				}
+			}
+			return indexer.IndexPublications(tx, row.ID)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to normalize DOI of publication %d: %w", row.ID, err)
+		}
+		normalized++
+	}
+
+	if normalized > 0 {
+		log.Printf("Normalized the DOIs of %d publications", normalized)
+	}
+	return nil
+}
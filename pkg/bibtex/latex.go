@@ -0,0 +1,195 @@
+package bibtex
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// combining maps LaTeX accent commands to Unicode combining marks
+var combining = map[rune]rune{
+	'\'': '́', // acute
+	'`':  '̀', // grave
+	'^':  '̂', // circumflex
+	'"':  '̈', // diaeresis
+	'~':  '̃', // tilde
+	'=':  '̄', // macron
+	'.':  '̇', // dot above
+	'u':  '̆', // breve
+	'v':  '̌', // caron
+	'H':  '̋', // double acute
+	'c':  '̧', // cedilla
+	'k':  '̨', // ogonek
+	'd':  '̣', // dot below
+	'r':  '̊', // ring above
+}
+
+// symbols maps LaTeX commands without arguments to the characters they produce
+var symbols = map[string]string{
+	"ss": "ß", "SS": "SS",
+	"ae": "æ", "AE": "Æ",
+	"oe": "œ", "OE": "Œ",
+	"aa": "å", "AA": "Å",
+	"o": "ø", "O": "Ø",
+	"l": "ł", "L": "Ł",
+	"i": "ı", "j": "ȷ",
+	"textendash": "–", "textemdash": "—",
+	"textquoteleft": "‘", "textquoteright": "’",
+	"textquotedblleft": "“", "textquotedblright": "”",
+	"textregistered": "®", "texttrademark": "™", "copyright": "©",
+	"textasciitilde": "~", "textbackslash": "\\",
+	"dots": "…", "ldots": "…",
+	"LaTeX": "LaTeX", "TeX": "TeX", "BibTeX": "BibTeX",
+}
+
+// Decode turns a LaTeX field value into plain Unicode text: accents and
+// escapes are resolved, grouping braces and $ are dropped, dashes and
+// quotes become typographic characters and whitespace is collapsed.
+func Decode(s string) string {
+	var out strings.Builder
+	in := []rune(s)
+
+	for i := 0; i < len(in); i++ {
+		r := in[i]
+		switch r {
+		case '{', '}', '$':
+			continue
+		case '~':
+			out.WriteRune(' ')
+			continue
+		case '-':
+			switch {
+			case i+2 < len(in) && in[i+1] == '-' && in[i+2] == '-':
+				out.WriteRune('—')
+				i += 2
+			case i+1 < len(in) && in[i+1] == '-':
+				out.WriteRune('–')
+				i++
+			default:
+				out.WriteRune('-')
+			}
+			continue
+		case '`':
+			if i+1 < len(in) && in[i+1] == '`' {
+				out.WriteRune('“')
+				i++
+				continue
+			}
+		case '\'':
+			if i+1 < len(in) && in[i+1] == '\'' {
+				out.WriteRune('”')
+				i++
+				continue
+			}
+		case '\\':
+			i = decodeCommand(in, i, &out)
+			continue
+		}
+		out.WriteRune(r)
+	}
+
+	return norm.NFC.String(strings.Join(strings.Fields(out.String()), " "))
+}
+
+// decodeCommand writes the text of the command starting at in[i] and
+// returns the index of its last rune
+func decodeCommand(in []rune, i int, out *strings.Builder) int {
+	if i+1 >= len(in) {
+		return i
+	}
+	next := in[i+1]
+
+	// Escaped special characters
+	if strings.ContainsRune("&%_$#{}\\ ", next) {
+		if next == '\\' {
+			// A line break
+			out.WriteRune(' ')
+		} else {
+			out.WriteRune(next)
+		}
+		return i + 1
+	}
+
+	// Accents: \'e, \'{e}, \c{c}, \v c
+	if mark, ok := combining[next]; ok {
+		j := i + 2
+		isLetter := unicode.IsLetter(next)
+		if isLetter {
+			// Letter accents need a brace or space before their argument,
+			// otherwise this is a command such as \url
+			if j >= len(in) || (in[j] != '{' && in[j] != ' ') {
+				return decodeWord(in, i, out)
+			}
+		}
+		for j < len(in) && (in[j] == ' ' || in[j] == '{') {
+			j++
+		}
+		if j >= len(in) {
+			return len(in) - 1
+		}
+		base := in[j]
+		// Accented dotless i and j
+		if base == '\\' && j+1 < len(in) && (in[j+1] == 'i' || in[j+1] == 'j') {
+			j++
+			base = in[j]
+		}
+		out.WriteRune(base)
+		out.WriteRune(mark)
+		for j+1 < len(in) && in[j+1] == '}' {
+			j++
+		}
+		return j
+	}
+
+	return decodeWord(in, i, out)
+}
+
+// decodeWord handles commands made of letters such as \ss or \emph{...}
+func decodeWord(in []rune, i int, out *strings.Builder) int {
+	j := i + 1
+	for j < len(in) && unicode.IsLetter(in[j]) {
+		j++
+	}
+	name := string(in[i+1 : j])
+	if name == "" {
+		// Unknown symbol command, keep the symbol
+		out.WriteRune(in[i+1])
+		return i + 1
+	}
+
+	if symbol, ok := symbols[name]; ok {
+		out.WriteString(symbol)
+		// A space or {} after a command only terminates it
+		if j < len(in) && in[j] == ' ' {
+			return j
+		}
+		if j+1 < len(in) && in[j] == '{' && in[j+1] == '}' {
+			return j + 1
+		}
+		return j - 1
+	}
+
+	// Formatting commands like \emph{x} or \textbf{x} keep their argument,
+	// which the caller emits as it skips the braces
+	return j - 1
+}
+
+// escaped are the characters Escape protects with a backslash
+var escaped = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`#`, `\#`,
+	`_`, `\_`,
+	`~`, `\textasciitilde{}`,
+)
+
+// Escape makes plain text safe inside a braced BibTeX value. Non-ASCII
+// characters are kept as UTF-8, which biber and modern BibTeX accept.
+func Escape(s string) string {
+	return escaped.Replace(s)
+}
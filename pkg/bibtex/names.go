@@ -0,0 +1,115 @@
+package bibtex
+
+import (
+	"strings"
+	"unicode"
+)
+
+// particles are lowercase name prefixes that belong to the last name
+var particles = map[string]bool{
+	"van": true, "von": true, "der": true, "den": true, "de": true, "del": true,
+	"della": true, "di": true, "da": true, "du": true, "le": true, "la": true,
+	"ter": true, "ten": true, "dos": true, "das": true, "bin": true, "al": true,
+}
+
+// SplitNames splits a decoded author or editor field into names in
+// "First Last" order. The trailing "and others" of truncated lists is dropped.
+func SplitNames(field string) []string {
+	var names []string
+	for _, name := range splitAnd(field) {
+		name = strings.TrimSpace(name)
+		if name == "" || strings.EqualFold(name, "others") {
+			continue
+		}
+		names = append(names, DisplayName(name))
+	}
+	return names
+}
+
+// splitAnd splits on the word "and" surrounded by whitespace
+func splitAnd(field string) []string {
+	words := strings.Fields(field)
+	var parts []string
+	start := 0
+	for i, word := range words {
+		if strings.EqualFold(word, "and") && i > start {
+			parts = append(parts, strings.Join(words[start:i], " "))
+			start = i + 1
+		}
+	}
+	if start < len(words) {
+		parts = append(parts, strings.Join(words[start:], " "))
+	}
+	return parts
+}
+
+// DisplayName turns "Last, First" and "Last, Jr, First" into "First Last"
+// and "First Last Jr". Names without a comma are returned as they are.
+func DisplayName(name string) string {
+	parts := strings.Split(name, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+
+	switch len(parts) {
+	case 2:
+		if parts[1] == "" {
+			return parts[0]
+		}
+		return parts[1] + " " + parts[0]
+	case 3:
+		return strings.TrimSpace(parts[2] + " " + parts[0] + " " + parts[1])
+	}
+	return strings.TrimSpace(name)
+}
+
+// SortName turns "First Last" into the unambiguous "Last, First" form used
+// when writing BibTeX. Particles such as "van" stay with the last name.
+// Single-word names and names written without spaces, such as most
+// Chinese, Japanese and Korean names, are returned unchanged.
+func SortName(name string) string {
+	first, last := SplitName(name)
+	if first == "" {
+		return last
+	}
+	return last + ", " + first
+}
+
+// LastName returns the family name part of a "First Last" name
+func LastName(name string) string {
+	_, last := SplitName(name)
+	return last
+}
+
+// SplitName splits a name into given names and family name. first is ""
+// for names that cannot be split, such as single words and CJK names.
+func SplitName(name string) (first, last string) {
+	name = strings.TrimSpace(name)
+	if strings.Contains(name, ",") {
+		parts := strings.SplitN(name, ",", 2)
+		return strings.TrimSpace(parts[1]), strings.TrimSpace(parts[0])
+	}
+
+	words := strings.Fields(name)
+	if len(words) < 2 || isCJK(name) {
+		return "", name
+	}
+
+	i := len(words) - 1
+	for i > 1 && particles[words[i-1]] {
+		i--
+	}
+	if particles[words[0]] && i == 1 {
+		return "", name
+	}
+	return strings.Join(words[:i], " "), strings.Join(words[i:], " ")
+}
+
+func isCJK(s string) bool {
+	for _, r := range s {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,349 @@
+package bibtex
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Entry is a BibTeX entry such as @article{key, title = {...}}.
+// Type and field names are lowercased; values are decoded from LaTeX.
+type Entry struct {
+	Type   string
+	Key    string
+	Fields map[string]string
+	// Line is where the entry starts in the input
+	Line int
+}
+
+// Field returns a field value or "" if it is missing
+func (e Entry) Field(name string) string {
+	return e.Fields[name]
+}
+
+// ParseError reports a malformed entry. Parsing resumes at the next entry.
+type ParseError struct {
+	Line int
+	Key  string
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	if e.Key != "" {
+		return fmt.Sprintf("line %d (%s): %s", e.Line, e.Key, e.Msg)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// months are the predefined month macros
+var months = map[string]string{
+	"jan": "1", "feb": "2", "mar": "3", "apr": "4", "may": "5", "jun": "6",
+	"jul": "7", "aug": "8", "sep": "9", "oct": "10", "nov": "11", "dec": "12",
+}
+
+// verbatim fields hold identifiers that are not LaTeX-decoded
+var verbatim = map[string]bool{"url": true, "doi": true, "eprint": true, "file": true}
+
+// Parse reads all entries from a BibTeX document. @string macros are
+// expanded, @comment and @preamble are skipped, and text outside entries is
+// ignored. Malformed entries are reported in errs and skipped.
+func Parse(input string) (entries []Entry, errs []*ParseError) {
+	p := &parser{input: []rune(input), line: 1, macros: make(map[string]string)}
+	for k, v := range months {
+		p.macros[k] = v
+	}
+
+	for p.skipTo('@') {
+		start := p.line
+		entry, err := p.parseEntry()
+		if err != nil {
+			err.Line = start
+			errs = append(errs, err)
+			continue
+		}
+		if entry != nil {
+			entry.Line = start
+			entries = append(entries, *entry)
+		}
+	}
+
+	return entries, errs
+}
+
+type parser struct {
+	input  []rune
+	pos    int
+	line   int
+	macros map[string]string
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *parser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *parser) advance() rune {
+	r := p.input[p.pos]
+	p.pos++
+	if r == '\n' {
+		p.line++
+	}
+	return r
+}
+
+// skipTo moves past the next occurrence of r and reports whether it was found
+func (p *parser) skipTo(r rune) bool {
+	for !p.eof() {
+		if p.advance() == r {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() {
+		r := p.peek()
+		if r == '%' {
+			// Line comment
+			for !p.eof() && p.peek() != '\n' {
+				p.advance()
+			}
+			continue
+		}
+		if !unicode.IsSpace(r) {
+			return
+		}
+		p.advance()
+	}
+}
+
+func (p *parser) identifier() string {
+	start := p.pos
+	for !p.eof() {
+		r := p.peek()
+		if unicode.IsSpace(r) || strings.ContainsRune("{}(),=#\"%@", r) {
+			break
+		}
+		p.advance()
+	}
+	return string(p.input[start:p.pos])
+}
+
+func (p *parser) fail(key, format string, args ...interface{}) *ParseError {
+	return &ParseError{Key: key, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseEntry parses what follows an '@'. It returns nil for entries that carry no data.
+func (p *parser) parseEntry() (*Entry, *ParseError) {
+	p.skipSpace()
+	kind := strings.ToLower(p.identifier())
+	if kind == "" {
+		return nil, p.fail("", "expected an entry type after '@'")
+	}
+
+	p.skipSpace()
+	var closing rune
+	switch p.peek() {
+	case '{':
+		closing = '}'
+	case '(':
+		closing = ')'
+	default:
+		return nil, p.fail("", "expected '{' after @%s", kind)
+	}
+	p.advance()
+
+	switch kind {
+	case "comment":
+		p.skipBalanced(closing)
+		return nil, nil
+	case "preamble":
+		p.skipBalanced(closing)
+		return nil, nil
+	case "string":
+		name, value, err := p.parseField("")
+		if err != nil {
+			p.recover()
+			return nil, err
+		}
+		p.macros[name] = value
+		p.skipSpace()
+		if p.peek() == closing {
+			p.advance()
+		}
+		return nil, nil
+	}
+
+	p.skipSpace()
+	key := p.identifier()
+	if key == "" {
+		p.recover()
+		return nil, p.fail("", "missing citation key in @%s", kind)
+	}
+
+	entry := &Entry{Type: kind, Key: key, Fields: make(map[string]string)}
+
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil, p.fail(key, "unexpected end of input; missing '%c'", closing)
+		}
+
+		switch p.peek() {
+		case closing:
+			p.advance()
+			return entry, nil
+		case ',':
+			p.advance()
+			continue
+		}
+
+		name, value, err := p.parseField(key)
+		if err != nil {
+			p.recover()
+			return nil, err
+		}
+		if _, dup := entry.Fields[name]; dup {
+			p.recover()
+			return nil, p.fail(key, "duplicate field %q", name)
+		}
+		if verbatim[name] {
+			entry.Fields[name] = strings.TrimSpace(value)
+		} else {
+			entry.Fields[name] = Decode(value)
+		}
+	}
+}
+
+// parseField parses name = value
+func (p *parser) parseField(key string) (string, string, *ParseError) {
+	name := strings.ToLower(p.identifier())
+	if name == "" {
+		return "", "", p.fail(key, "expected a field name, found %q", string(p.peek()))
+	}
+
+	p.skipSpace()
+	if p.peek() != '=' {
+		return "", "", p.fail(key, "expected '=' after field %q", name)
+	}
+	p.advance()
+
+	value, err := p.parseValue(key, name)
+	if err != nil {
+		return "", "", err
+	}
+	return name, value, nil
+}
+
+// parseValue parses a value made of {braced} or "quoted" strings, numbers
+// and macro names joined with #. The result is still LaTeX.
+func (p *parser) parseValue(key, field string) (string, *ParseError) {
+	var value strings.Builder
+
+	for {
+		p.skipSpace()
+		switch r := p.peek(); {
+		case r == '{':
+			p.advance()
+			raw, ok := p.braced('}')
+			if !ok {
+				return "", p.fail(key, "unbalanced braces in field %q", field)
+			}
+			value.WriteString(raw)
+		case r == '"':
+			p.advance()
+			raw, ok := p.braced('"')
+			if !ok {
+				return "", p.fail(key, "unterminated quote in field %q", field)
+			}
+			value.WriteString(raw)
+		case unicode.IsDigit(r):
+			start := p.pos
+			for !p.eof() && unicode.IsDigit(p.peek()) {
+				p.advance()
+			}
+			value.WriteString(string(p.input[start:p.pos]))
+		default:
+			name := p.identifier()
+			if name == "" {
+				return "", p.fail(key, "expected a value for field %q", field)
+			}
+			macro, ok := p.macros[strings.ToLower(name)]
+			if !ok {
+				return "", p.fail(key, "undefined macro %q in field %q", name, field)
+			}
+			value.WriteString(macro)
+		}
+
+		p.skipSpace()
+		if p.peek() != '#' {
+			break
+		}
+		p.advance()
+	}
+
+	return value.String(), nil
+}
+
+// braced reads up to the terminator at brace depth zero, keeping inner braces
+func (p *parser) braced(terminator rune) (string, bool) {
+	var out strings.Builder
+	depth := 0
+
+	for !p.eof() {
+		r := p.advance()
+		switch {
+		case r == '\\' && !p.eof():
+			// Keep escapes like \" or \{ intact for Decode
+			out.WriteRune(r)
+			out.WriteRune(p.advance())
+			continue
+		case r == terminator && depth == 0:
+			return out.String(), true
+		case r == '{':
+			depth++
+		case r == '}':
+			if depth == 0 {
+				return "", false
+			}
+			depth--
+		}
+		out.WriteRune(r)
+	}
+
+	return "", false
+}
+
+// skipBalanced skips to the closing delimiter of the current entry
+func (p *parser) skipBalanced(closing rune) {
+	depth := 0
+	for !p.eof() {
+		r := p.advance()
+		switch {
+		case r == '{' || r == '(' && closing == ')':
+			depth++
+		case r == closing && depth == 0:
+			return
+		case r == '}' || r == ')' && closing == ')':
+			depth--
+		}
+	}
+}
+
+// recover skips the rest of a malformed entry: up to the next '@' at the
+// start of a line, so one broken entry does not swallow the following ones
+func (p *parser) recover() {
+	for !p.eof() {
+		if p.peek() == '@' && (p.pos == 0 || p.input[p.pos-1] == '\n') {
+			return
+		}
+		p.advance()
+	}
+}
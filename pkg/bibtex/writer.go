@@ -0,0 +1,59 @@
+package bibtex
+
+import (
+	"bufio"
+	"io"
+	"sort"
+)
+
+// fieldOrder is the order fields are written in; other fields follow alphabetically
+var fieldOrder = []string{
+	"author", "editor", "title", "journal", "booktitle", "year", "month",
+	"volume", "number", "pages", "publisher", "doi", "url", "keywords", "abstract",
+}
+
+// Write writes entries as BibTeX. Values are plain text and are escaped;
+// url, doi and similar identifier fields are written verbatim.
+func Write(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+
+	rank := make(map[string]int, len(fieldOrder))
+	for i, name := range fieldOrder {
+		rank[name] = i + 1
+	}
+
+	for i, entry := range entries {
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+
+		names := make([]string, 0, len(entry.Fields))
+		for name, value := range entry.Fields {
+			if value != "" {
+				names = append(names, name)
+			}
+		}
+		sort.Slice(names, func(a, b int) bool {
+			ra, rb := rank[names[a]], rank[names[b]]
+			if ra == 0 || rb == 0 {
+				if ra != rb {
+					return ra != 0
+				}
+				return names[a] < names[b]
+			}
+			return ra < rb
+		})
+
+		bw.WriteString("@" + entry.Type + "{" + entry.Key + ",\n")
+		for _, name := range names {
+			value := entry.Fields[name]
+			if !verbatim[name] {
+				value = Escape(value)
+			}
+			bw.WriteString("  " + name + " = {" + value + "},\n")
+		}
+		bw.WriteString("}\n")
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,60 @@
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/olivere/elastic/v7"
+)
+
+// ResolveAlias returns the concrete indices behind a name. concrete is true when
+// the name is itself an index rather than an alias; both are empty if neither exists.
+func (c *Client) ResolveAlias(ctx context.Context, name string) (indices []string, concrete bool, err error) {
+	result, err := c.IndexGet(name).Do(ctx)
+	if elastic.IsNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to resolve %s: %w", name, err)
+	}
+
+	for index := range result {
+		if index == name {
+			concrete = true
+		}
+		indices = append(indices, index)
+	}
+
+	return indices, concrete, nil
+}
+
+// SwapAlias atomically points alias at newIndex and returns the indices it
+// pointed at before. A concrete index with the alias's name (as created
+// implicitly before indices were managed) is deleted in the same request.
+func (c *Client) SwapAlias(ctx context.Context, alias, newIndex string) ([]string, error) {
+	oldIndices, concrete, err := c.ResolveAlias(ctx, alias)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []elastic.AliasAction
+	var previous []string
+	for _, index := range oldIndices {
+		if index == newIndex {
+			continue
+		}
+		if concrete && index == alias {
+			actions = append(actions, elastic.NewAliasRemoveIndexAction(index))
+			continue
+		}
+		actions = append(actions, elastic.NewAliasRemoveAction(alias).Index(index))
+		previous = append(previous, index)
+	}
+	actions = append(actions, elastic.NewAliasAddAction(alias).Index(newIndex))
+
+	if _, err := c.Alias().Action(actions...).Do(ctx); err != nil {
+		return nil, fmt.Errorf("failed to swap alias %s: %w", alias, err)
+	}
+
+	return previous, nil
+}
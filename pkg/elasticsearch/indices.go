@@ -0,0 +1,159 @@
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/olivere/elastic/v7"
+)
+
+// PutTemplate installs the spec as an index template, so any index created
+// under the alias's versioned names gets the managed settings and mapping
+func (c *Client) PutTemplate(ctx context.Context, spec IndexSpec) error {
+	body, err := spec.body()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.IndexPutIndexTemplate(spec.Alias).
+		BodyJson(map[string]interface{}{
+			"index_patterns": []string{spec.pattern()},
+			"version":        spec.Version,
+			"template":       body,
+		}).
+		Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to put index template %s: %w", spec.Alias, err)
+	}
+
+	return nil
+}
+
+// CreateIndexFromSpec creates a concrete index from the spec, optionally overriding settings
+func (c *Client) CreateIndexFromSpec(ctx context.Context, spec IndexSpec, name string, settings map[string]interface{}) error {
+	body, err := spec.body()
+	if err != nil {
+		return err
+	}
+	for key, value := range settings {
+		body["settings"].(map[string]interface{})[key] = value
+	}
+
+	if _, err := c.CreateIndex(name).BodyJson(body).Do(ctx); err != nil {
+		return fmt.Errorf("failed to create index %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// EnsureIndices installs the templates of all managed indices, creates the
+// ones that do not exist yet and validates the mapping of the rest
+func (c *Client) EnsureIndices(ctx context.Context) error {
+	for _, spec := range Specs() {
+		if err := c.EnsureIndex(ctx, spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureIndex makes sure the alias points at an index built from the spec.
+// A missing index is created; an index with an outdated or incompatible
+// mapping is reported as an error telling the operator to reindex.
+func (c *Client) EnsureIndex(ctx context.Context, spec IndexSpec) error {
+	if err := c.PutTemplate(ctx, spec); err != nil {
+		return err
+	}
+
+	indices, concrete, err := c.ResolveAlias(ctx, spec.Alias)
+	if err != nil {
+		return err
+	}
+
+	if concrete {
+		return fmt.Errorf("index %s was created without a managed mapping; run `reindex -index %s` to migrate it", spec.Alias, spec.Alias)
+	}
+
+	if len(indices) == 0 {
+		// A fixed name makes concurrent first starts converge on one index
+		name := fmt.Sprintf("%s_v%d", spec.Alias, spec.Version)
+		body, err := spec.body()
+		if err != nil {
+			return err
+		}
+		body["aliases"] = map[string]interface{}{spec.Alias: map[string]interface{}{}}
+
+		_, err = c.CreateIndex(name).BodyJson(body).Do(ctx)
+		if err != nil && !isAlreadyExists(err) {
+			return fmt.Errorf("failed to create index %s: %w", name, err)
+		}
+		return nil
+	}
+
+	for _, index := range indices {
+		if err := c.ValidateIndex(ctx, spec, index); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateIndex checks the mapping version and field types of a concrete index against the spec
+func (c *Client) ValidateIndex(ctx context.Context, spec IndexSpec, index string) error {
+	result, err := c.GetMapping().Index(index).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get mapping of %s: %w", index, err)
+	}
+
+	entry, _ := result[index].(map[string]interface{})
+	mappings, _ := entry["mappings"].(map[string]interface{})
+
+	version := -1
+	if meta, ok := mappings["_meta"].(map[string]interface{}); ok {
+		if v, ok := meta["version"].(float64); ok {
+			version = int(v)
+		}
+	}
+	if version != spec.Version {
+		return fmt.Errorf("index %s has mapping version %d but version %d is required; run `reindex -index %s` to rebuild it", index, version, spec.Version, spec.Alias)
+	}
+
+	var mismatches []string
+	for path, want := range spec.Fields {
+		if got := fieldType(mappings, path); got != want {
+			mismatches = append(mismatches, fmt.Sprintf("%s is %q, expected %q", path, got, want))
+		}
+	}
+	if len(mismatches) > 0 {
+		sort.Strings(mismatches)
+		return fmt.Errorf("index %s has an incompatible mapping (%s); run `reindex -index %s` to rebuild it", index, strings.Join(mismatches, "; "), spec.Alias)
+	}
+
+	return nil
+}
+
+// fieldType looks up the type of a field path such as "title.keyword",
+// where everything after the first dot names a multi-field
+func fieldType(mappings map[string]interface{}, path string) string {
+	name, sub, _ := strings.Cut(path, ".")
+
+	properties, _ := mappings["properties"].(map[string]interface{})
+	field, _ := properties[name].(map[string]interface{})
+	if sub != "" {
+		fields, _ := field["fields"].(map[string]interface{})
+		field, _ = fields[sub].(map[string]interface{})
+	}
+
+	fieldType, _ := field["type"].(string)
+	return fieldType
+}
+
+func isAlreadyExists(err error) bool {
+	if e, ok := err.(*elastic.Error); ok && e.Details != nil {
+		return e.Details.Type == "resource_already_exists_exception"
+	}
+	return false
+}
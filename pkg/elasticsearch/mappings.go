@@ -0,0 +1,244 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Aliases the application reads and writes through. The concrete indices
+// behind them are versioned and created from the specs below.
+const (
+	PublicationsAlias = "publications"
+	AuthorsAlias      = "authors"
+)
+
+// IndexSpec describes a managed index: its settings, its mapping and the
+// version stamped into the mapping's _meta. Bump Version whenever Settings or
+// Mappings change; indices built from an older version have to be rebuilt
+// with `reindex`.
+type IndexSpec struct {
+	Alias    string
+	Version  int
+	Settings string
+	Mappings string
+	// Fields lists the field types queries depend on; startup refuses to
+	// run against an index where any of them differ
+	Fields map[string]string
+}
+
+// analysis is shared by all indices
+const analysis = `{
+	"filter": {
+		"autocomplete_edge": {"type": "edge_ngram", "min_gram": 1, "max_gram": 20},
+		"shingle_2_3":       {"type": "shingle", "min_shingle_size": 2, "max_shingle_size": 3}
+	},
+	"analyzer": {
+		"folding": {
+			"type": "custom",
+			"tokenizer": "standard",
+			"filter": ["lowercase", "asciifolding"]
+		},
+		"autocomplete": {
+			"type": "custom",
+			"tokenizer": "standard",
+			"filter": ["lowercase", "asciifolding", "autocomplete_edge"]
+		},
+		"shingle": {
+			"type": "custom",
+			"tokenizer": "standard",
+			"filter": ["lowercase", "shingle_2_3"]
+		},
+		"cjk_text": {
+			"type": "custom",
+			"tokenizer": "standard",
+			"filter": ["cjk_width", "lowercase", "cjk_bigram"]
+		}
+	},
+	"normalizer": {
+		"lowercase": {"type": "custom", "filter": ["lowercase", "asciifolding"]}
+	}
+}`
+
+// PublicationsIndex is the spec of the publications index.
+// Titles and abstracts carry a cjk subfield that indexes Chinese, Japanese
+// and Korean text as overlapping bigrams, since the standard tokenizer
+// reduces it to single characters. title.shingle feeds the phrase suggester.
+// fulltext holds the text of the PDF; its postings store offsets so long
+// bodies highlight without being re-analyzed.
+var PublicationsIndex = IndexSpec{
+	Alias:   PublicationsAlias,
+	Version: 5,
+	Settings: `{
+		"number_of_shards": 1,
+		"number_of_replicas": 1,
+		"analysis": ` + analysis + `
+	}`,
+	Mappings: `{
+		"dynamic": false,
+		"properties": {
+			"id":       {"type": "long"},
+			"title": {
+				"type": "text",
+				"analyzer": "folding",
+				"fields": {
+					"keyword":      {"type": "keyword", "ignore_above": 512},
+					"cjk":          {"type": "text", "analyzer": "cjk_text"},
+					"autocomplete": {"type": "text", "analyzer": "autocomplete", "search_analyzer": "folding"},
+					"shingle":      {"type": "text", "analyzer": "shingle"}
+				}
+			},
+			"abstract": {
+				"type": "text",
+				"analyzer": "folding",
+				"fields": {
+					"cjk": {"type": "text", "analyzer": "cjk_text"}
+				}
+			},
+			"authors": {
+				"type": "text",
+				"analyzer": "folding",
+				"fields": {
+					"keyword":      {"type": "keyword", "ignore_above": 256},
+					"autocomplete": {"type": "text", "analyzer": "autocomplete", "search_analyzer": "folding"}
+				}
+			},
+			"keywords": {
+				"type": "text",
+				"analyzer": "folding",
+				"fields": {
+					"keyword":      {"type": "keyword", "ignore_above": 256},
+					"autocomplete": {"type": "text", "analyzer": "autocomplete", "search_analyzer": "folding"}
+				}
+			},
+			"journal": {
+				"type": "text",
+				"analyzer": "folding",
+				"fields": {
+					"keyword":      {"type": "keyword", "ignore_above": 256},
+					"autocomplete": {"type": "text", "analyzer": "autocomplete", "search_analyzer": "folding"}
+				}
+			},
+			"fulltext": {
+				"type": "text",
+				"analyzer": "folding",
+				"index_options": "offsets",
+				"fields": {
+					"cjk": {"type": "text", "analyzer": "cjk_text", "index_options": "offsets"}
+				}
+			},
+			"doi":              {"type": "keyword", "normalizer": "lowercase"},
+			"publication_date": {"type": "date"},
+			"citation_count":   {"type": "integer"},
+			"has_pdf":          {"type": "boolean"}
+		}
+	}`,
+	Fields: map[string]string{
+		"id":                    "long",
+		"title":                 "text",
+		"title.keyword":         "keyword",
+		"title.cjk":             "text",
+		"title.autocomplete":    "text",
+		"title.shingle":         "text",
+		"abstract":              "text",
+		"abstract.cjk":          "text",
+		"authors":               "text",
+		"authors.keyword":       "keyword",
+		"authors.autocomplete":  "text",
+		"keywords":              "text",
+		"keywords.keyword":      "keyword",
+		"keywords.autocomplete": "text",
+		"journal":               "text",
+		"journal.keyword":       "keyword",
+		"journal.autocomplete":  "text",
+		"fulltext":              "text",
+		"fulltext.cjk":          "text",
+		"doi":                   "keyword",
+		"publication_date":      "date",
+		"citation_count":        "integer",
+		"has_pdf":               "boolean",
+	},
+}
+
+// AuthorsIndex is the spec of the authors index
+var AuthorsIndex = IndexSpec{
+	Alias:   AuthorsAlias,
+	Version: 1,
+	Settings: `{
+		"number_of_shards": 1,
+		"number_of_replicas": 1,
+		"analysis": ` + analysis + `
+	}`,
+	Mappings: `{
+		"dynamic": false,
+		"properties": {
+			"id": {"type": "long"},
+			"name": {
+				"type": "text",
+				"analyzer": "folding",
+				"fields": {
+					"keyword":      {"type": "keyword", "ignore_above": 256},
+					"autocomplete": {"type": "text", "analyzer": "autocomplete", "search_analyzer": "folding"}
+				}
+			},
+			"institution": {
+				"type": "text",
+				"analyzer": "folding",
+				"fields": {
+					"keyword": {"type": "keyword", "ignore_above": 256}
+				}
+			},
+			"email": {"type": "keyword", "normalizer": "lowercase"}
+		}
+	}`,
+	Fields: map[string]string{
+		"id":                "long",
+		"name":              "text",
+		"name.keyword":      "keyword",
+		"name.autocomplete": "text",
+		"institution":       "text",
+		"email":             "keyword",
+	},
+}
+
+// Specs returns every managed index
+func Specs() []IndexSpec {
+	return []IndexSpec{PublicationsIndex, AuthorsIndex}
+}
+
+// SpecFor returns the spec of the index behind alias
+func SpecFor(alias string) (IndexSpec, bool) {
+	for _, spec := range Specs() {
+		if spec.Alias == alias {
+			return spec, true
+		}
+	}
+	return IndexSpec{}, false
+}
+
+// body builds the create-index or template body, stamping the version into _meta
+func (s IndexSpec) body() (map[string]interface{}, error) {
+	var settings, mappings map[string]interface{}
+	if err := json.Unmarshal([]byte(s.Settings), &settings); err != nil {
+		return nil, fmt.Errorf("invalid settings for %s: %w", s.Alias, err)
+	}
+	if err := json.Unmarshal([]byte(s.Mappings), &mappings); err != nil {
+		return nil, fmt.Errorf("invalid mappings for %s: %w", s.Alias, err)
+	}
+	mappings["_meta"] = map[string]interface{}{"version": s.Version}
+
+	return map[string]interface{}{
+		"settings": settings,
+		"mappings": mappings,
+	}, nil
+}
+
+// pattern matches every concrete index created for the spec
+func (s IndexSpec) pattern() string {
+	return s.Alias + "_v*"
+}
+
+// VersionedIndexName returns a fresh concrete index name to put behind the alias
+func (s IndexSpec) VersionedIndexName() string {
+	return fmt.Sprintf("%s_v%d_%s", s.Alias, s.Version, time.Now().Format("20060102150405"))
+}
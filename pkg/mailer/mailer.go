@@ -0,0 +1,160 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"freescholar-backend/config"
+)
+
+// ErrQueueFull is returned when the send queue cannot take more messages
+var ErrQueueFull = errors.New("mail queue is full")
+
+// Message is a rendered email ready to be delivered
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Driver delivers a single message
+type Driver interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Mailer renders templated emails and delivers them from a background queue
+// so handlers never block on SMTP
+type Mailer struct {
+	driver     Driver
+	from       string
+	siteURL    string
+	maxRetries int
+	queue      chan *Message
+	wg         sync.WaitGroup
+}
+
+// New creates a mailer with the driver selected in the configuration and starts its workers
+func New(cfg config.EmailConfig) (*Mailer, error) {
+	var driver Driver
+	switch cfg.Driver {
+	case "", "smtp":
+		driver = NewSMTPDriver(cfg)
+	case "outbox":
+		driver = NewOutboxDriver(cfg.OutboxDir)
+	default:
+		return nil, fmt.Errorf("unknown email driver %q", cfg.Driver)
+	}
+
+	from := cfg.From
+	if from == "" {
+		from = cfg.User
+	}
+	if cfg.FromName != "" {
+		from = fmt.Sprintf("%s <%s>", cfg.FromName, from)
+	}
+
+	return NewWithDriver(driver, from, cfg), nil
+}
+
+// NewWithDriver creates a mailer around an existing driver and starts its workers
+func NewWithDriver(driver Driver, from string, cfg config.EmailConfig) *Mailer {
+	queueSize := cfg.QueueSize
+	if queueSize < 1 {
+		queueSize = 100
+	}
+	workers := cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	m := &Mailer{
+		driver:     driver,
+		from:       from,
+		siteURL:    cfg.SiteURL,
+		maxRetries: cfg.MaxRetries,
+		queue:      make(chan *Message, queueSize),
+	}
+
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		go m.work()
+	}
+
+	return m
+}
+
+// SiteURL returns the base URL used for links in emails
+func (m *Mailer) SiteURL() string {
+	return m.siteURL
+}
+
+// Enqueue schedules a message for delivery without waiting for it to be sent
+func (m *Mailer) Enqueue(msg *Message) error {
+	if msg.From == "" {
+		msg.From = m.from
+	}
+
+	select {
+	case m.queue <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// SendTemplate renders the named template for a recipient and enqueues it
+func (m *Mailer) SendTemplate(to, name string, data map[string]interface{}) error {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	if _, ok := data["SiteURL"]; !ok {
+		data["SiteURL"] = m.siteURL
+	}
+
+	msg, err := Render(name, data)
+	if err != nil {
+		return err
+	}
+	msg.To = []string{to}
+
+	return m.Enqueue(msg)
+}
+
+// Close stops accepting messages and waits for the queue to drain
+func (m *Mailer) Close() {
+	close(m.queue)
+	m.wg.Wait()
+}
+
+// work delivers queued messages, retrying transient failures with exponential backoff
+func (m *Mailer) work() {
+	defer m.wg.Done()
+
+	for msg := range m.queue {
+		backoff := time.Second
+		for attempt := 0; ; attempt++ {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			err := m.driver.Send(ctx, msg)
+			cancel()
+
+			if err == nil {
+				break
+			}
+
+			if !IsTransient(err) || attempt >= m.maxRetries {
+				log.Printf("Failed to send email %q to %v: %v", msg.Subject, msg.To, err)
+				break
+			}
+
+			log.Printf("Retrying email %q to %v in %s: %v", msg.Subject, msg.To, backoff, err)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// OutboxDriver captures messages instead of sending them. Each message is
+// written to the outbox directory as an .eml file and logged, which is what
+// local development and tests use instead of a real SMTP server.
+type OutboxDriver struct {
+	dir      string
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewOutboxDriver creates a new outbox driver writing to dir. With an empty dir
+// messages are only kept in memory and logged.
+func NewOutboxDriver(dir string) *OutboxDriver {
+	return &OutboxDriver{dir: dir}
+}
+
+// Send captures a message
+func (d *OutboxDriver) Send(ctx context.Context, msg *Message) error {
+	d.mu.Lock()
+	d.messages = append(d.messages, *msg)
+	count := len(d.messages)
+	d.mu.Unlock()
+
+	if d.dir == "" {
+		log.Printf("Outbox: %q to %v", msg.Subject, msg.To)
+		return nil
+	}
+
+	body, err := buildMIME(msg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(d.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%04d.eml", time.Now().Format("20060102T150405.000000000"), count)
+	path := filepath.Join(d.dir, name)
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		return fmt.Errorf("failed to write outbox message: %w", err)
+	}
+
+	log.Printf("Outbox: %q to %v written to %s", msg.Subject, msg.To, path)
+	return nil
+}
+
+// Messages returns a copy of every message captured so far
+func (d *OutboxDriver) Messages() []Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	messages := make([]Message, len(d.messages))
+	copy(messages, d.messages)
+	return messages
+}
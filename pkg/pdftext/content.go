@@ -0,0 +1,338 @@
+package pdftext
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// operand is a value on the operand stack of a content stream
+type operand struct {
+	str   []byte
+	isStr bool
+	num   float64
+	isNum bool
+	array []operand
+	isArr bool
+}
+
+// parseContent runs the text operators of a content stream and returns its text
+func parseContent(content []byte) string {
+	l := &lexer{data: content}
+	var out strings.Builder
+	var stack []operand
+	var lastY float64
+	inText := false
+
+	show := func(s []byte) {
+		if text := decodeString(s); printable(text) {
+			out.WriteString(text)
+		}
+	}
+	newline := func() {
+		out.WriteByte('\n')
+	}
+
+	for {
+		tok, ok := l.next()
+		if !ok {
+			break
+		}
+		if tok.operator == "" {
+			stack = append(stack, tok.value)
+			continue
+		}
+
+		switch tok.operator {
+		case "BT":
+			inText = true
+		case "ET":
+			inText = false
+			newline()
+		case "ID":
+			l.skipInlineImage()
+		}
+
+		if inText {
+			switch tok.operator {
+			case "Tj":
+				if n := len(stack); n > 0 && stack[n-1].isStr {
+					show(stack[n-1].str)
+				}
+			case "'":
+				newline()
+				if n := len(stack); n > 0 && stack[n-1].isStr {
+					show(stack[n-1].str)
+				}
+			case "\"":
+				newline()
+				if n := len(stack); n > 0 && stack[n-1].isStr {
+					show(stack[n-1].str)
+				}
+			case "TJ":
+				if n := len(stack); n > 0 && stack[n-1].isArr {
+					for _, item := range stack[n-1].array {
+						if item.isStr {
+							show(item.str)
+						} else if item.isNum && item.num < -200 {
+							// A large negative kern is a word space
+							out.WriteByte(' ')
+						}
+					}
+				}
+			case "Td", "TD":
+				if n := len(stack); n >= 2 && stack[n-1].isNum && stack[n-2].isNum {
+					if stack[n-1].num != 0 {
+						newline()
+					} else if stack[n-2].num > 0 {
+						out.WriteByte(' ')
+					}
+				}
+			case "T*":
+				newline()
+			case "Tm":
+				if n := len(stack); n >= 6 && stack[n-1].isNum {
+					if y := stack[n-1].num; y != lastY {
+						newline()
+						lastY = y
+					} else {
+						out.WriteByte(' ')
+					}
+				}
+			}
+		}
+
+		stack = stack[:0]
+	}
+
+	return out.String()
+}
+
+// token is an operand or an operator
+type token struct {
+	value    operand
+	operator string
+}
+
+type lexer struct {
+	data []byte
+	pos  int
+}
+
+func isDelimiter(b byte) bool {
+	return strings.IndexByte("()<>[]{}/%", b) >= 0
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n' || b == '\f' || b == 0
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		b := l.data[l.pos]
+		if b == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isSpace(b) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// next returns the next token, or false at the end of the stream
+func (l *lexer) next() (token, bool) {
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.data) {
+			return token{}, false
+		}
+
+		switch b := l.data[l.pos]; {
+		case b == '(':
+			l.pos++
+			return token{value: operand{str: l.literal(), isStr: true}}, true
+		case b == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+			// Dictionaries are only operands of operators we ignore
+			l.skipDict()
+			return token{value: operand{}}, true
+		case b == '<':
+			l.pos++
+			return token{value: operand{str: l.hex(), isStr: true}}, true
+		case b == '[':
+			l.pos++
+			return token{value: l.array()}, true
+		case b == ']' || b == '>' || b == ')' || b == '{' || b == '}':
+			l.pos++
+			continue
+		case b == '/':
+			l.pos++
+			l.word()
+			return token{value: operand{}}, true
+		}
+
+		word := l.word()
+		if word == "" {
+			l.pos++
+			continue
+		}
+		if num, err := strconv.ParseFloat(word, 64); err == nil {
+			return token{value: operand{num: num, isNum: true}}, true
+		}
+		return token{operator: word}, true
+	}
+}
+
+// word reads a run of regular characters
+func (l *lexer) word() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isSpace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// literal reads a (string) after its opening parenthesis
+func (l *lexer) literal() []byte {
+	var out []byte
+	depth := 0
+
+	for l.pos < len(l.data) {
+		b := l.data[l.pos]
+		l.pos++
+
+		switch b {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return out
+			}
+			depth--
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b', 'f':
+			case '\r':
+				// Line continuation
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				code := int(e - '0')
+				for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+					code = code*8 + int(l.data[l.pos]-'0')
+					l.pos++
+				}
+				out = append(out, byte(code))
+			default:
+				out = append(out, e)
+			}
+			continue
+		}
+		out = append(out, b)
+	}
+
+	return out
+}
+
+// hex reads a <hex string> after its opening bracket
+func (l *lexer) hex() []byte {
+	end := bytes.IndexByte(l.data[l.pos:], '>')
+	if end < 0 {
+		end = len(l.data) - l.pos
+	}
+	digits := make([]byte, 0, end)
+	for _, b := range l.data[l.pos : l.pos+end] {
+		if !isSpace(b) {
+			digits = append(digits, b)
+		}
+	}
+	l.pos += end + 1
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	out := make([]byte, 0, len(digits)/2)
+	for i := 0; i < len(digits); i += 2 {
+		v, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			return nil
+		}
+		out = append(out, byte(v))
+	}
+	return out
+}
+
+// array reads an [array] after its opening bracket
+func (l *lexer) array() operand {
+	arr := operand{isArr: true}
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.data) {
+			return arr
+		}
+		if l.data[l.pos] == ']' {
+			l.pos++
+			return arr
+		}
+		tok, ok := l.next()
+		if !ok {
+			return arr
+		}
+		if tok.operator == "" {
+			arr.array = append(arr.array, tok.value)
+		}
+	}
+}
+
+// skipDict skips a <<dictionary>>, including nested ones
+func (l *lexer) skipDict() {
+	depth := 0
+	for l.pos+1 < len(l.data) {
+		switch {
+		case l.data[l.pos] == '<' && l.data[l.pos+1] == '<':
+			depth++
+			l.pos += 2
+		case l.data[l.pos] == '>' && l.data[l.pos+1] == '>':
+			depth--
+			l.pos += 2
+			if depth == 0 {
+				return
+			}
+		case l.data[l.pos] == '(':
+			l.pos++
+			l.literal()
+		default:
+			l.pos++
+		}
+	}
+	l.pos = len(l.data)
+}
+
+// skipInlineImage skips the binary data of an inline image up to its EI operator
+func (l *lexer) skipInlineImage() {
+	for l.pos+2 < len(l.data) {
+		if isSpace(l.data[l.pos]) && l.data[l.pos+1] == 'E' && l.data[l.pos+2] == 'I' &&
+			(l.pos+3 == len(l.data) || isSpace(l.data[l.pos+3])) {
+			l.pos += 3
+			return
+		}
+		l.pos++
+	}
+	l.pos = len(l.data)
+}
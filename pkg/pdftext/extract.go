@@ -0,0 +1,232 @@
+// Package pdftext extracts plain text from PDF files. It reads the text
+// operators of uncompressed and Flate-compressed content streams, which
+// covers most PDFs written by TeX and word processors. Fonts that map glyphs
+// through a ToUnicode CMap only, such as Identity-H CID fonts, yield no text,
+// so callers should treat the result as best effort.
+package pdftext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	// ErrEncrypted is returned for encrypted PDFs, whose streams cannot be read
+	ErrEncrypted = errors.New("pdftext: encrypted PDF")
+	// ErrNotPDF is returned when the data does not start with a PDF header
+	ErrNotPDF = errors.New("pdftext: not a PDF")
+)
+
+// maxStreamSize caps the decompressed size of one stream
+const maxStreamSize = 32 << 20
+
+// skippedStreams are dictionary entries of streams that never hold page text:
+// images, embedded fonts, object, cross-reference and metadata streams
+var skippedStreams = [][]byte{
+	[]byte("/Image"),
+	[]byte("/Length1"),
+	[]byte("/Length2"),
+	[]byte("/Length3"),
+	[]byte("/FontFile"),
+	[]byte("/Type1C"),
+	[]byte("/CIDFontType0C"),
+	[]byte("/OpenType"),
+	[]byte("/XRef"),
+	[]byte("/Metadata"),
+	[]byte("/XML"),
+	[]byte("/EmbeddedFile"),
+	[]byte("/ObjStm"),
+}
+
+// Extract returns the text of a PDF, one line per line of text in the
+// document order of its content streams
+func Extract(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return "", ErrNotPDF
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return "", ErrEncrypted
+	}
+
+	var out strings.Builder
+	for _, content := range contentStreams(data) {
+		text := parseContent(content)
+		if text == "" {
+			continue
+		}
+		out.WriteString(text)
+		out.WriteByte('\n')
+	}
+
+	return cleanup(out.String()), nil
+}
+
+// contentStreams returns the decoded streams that may contain text operators
+func contentStreams(data []byte) [][]byte {
+	var streams [][]byte
+
+	for pos := 0; ; {
+		i := bytes.Index(data[pos:], []byte("stream"))
+		if i < 0 {
+			break
+		}
+		start := pos + i
+		pos = start + len("stream")
+
+		// "stream" must follow a dictionary; this also skips "endstream"
+		if !bytes.HasSuffix(bytes.TrimRight(data[:start], " \t\r\n"), []byte(">>")) {
+			continue
+		}
+
+		// The dictionary runs from the "N 0 obj" line to the keyword
+		dictStart := bytes.LastIndex(data[:start], []byte("obj"))
+		if dictStart < 0 {
+			continue
+		}
+		dict := data[dictStart:start]
+
+		// Stream data starts after the end-of-line that follows the keyword
+		body := pos
+		if body < len(data) && data[body] == '\r' {
+			body++
+		}
+		if body < len(data) && data[body] == '\n' {
+			body++
+		}
+		end := bytes.Index(data[body:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		raw := bytes.TrimRight(data[body:body+end], "\r\n")
+		pos = body + end + len("endstream")
+
+		if skipStream(dict) {
+			continue
+		}
+		if content, ok := decodeStream(dict, raw); ok && bytes.Contains(content, []byte("BT")) {
+			streams = append(streams, content)
+		}
+	}
+
+	return streams
+}
+
+func skipStream(dict []byte) bool {
+	for _, marker := range skippedStreams {
+		if bytes.Contains(dict, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeStream applies the stream's filter. Only FlateDecode is supported;
+// streams with other filters are reported as unreadable.
+func decodeStream(dict, raw []byte) ([]byte, bool) {
+	if !bytes.Contains(dict, []byte("/Filter")) {
+		return raw, true
+	}
+
+	filter := dict[bytes.Index(dict, []byte("/Filter"))+len("/Filter"):]
+	filter = bytes.TrimLeft(filter, " \t\r\n")
+	flate := bytes.HasPrefix(filter, []byte("/FlateDecode")) || bytes.HasPrefix(filter, []byte("/Fl"))
+	if bytes.HasPrefix(filter, []byte("[")) {
+		// A filter chain is only readable if Flate is all of it
+		names := bytes.Fields(bytes.Trim(filter[:bytes.IndexByte(filter, ']')+1], "[]"))
+		flate = len(names) == 1 && (bytes.Equal(names[0], []byte("/FlateDecode")) || bytes.Equal(names[0], []byte("/Fl")))
+	}
+	if !flate {
+		return nil, false
+	}
+
+	reader, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, false
+	}
+	defer reader.Close()
+
+	// Keep what was inflated before any corruption
+	content, _ := io.ReadAll(io.LimitReader(reader, maxStreamSize))
+	return content, len(content) > 0
+}
+
+// cleanup drops blank lines and collapses runs of spaces
+func cleanup(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// decodeString turns the bytes of a PDF string into text. Strings with a
+// byte order mark are UTF-16BE; others are read as WinAnsi, which matches
+// PDFDocEncoding and Latin-1 for the characters that matter.
+func decodeString(s []byte) string {
+	if len(s) >= 2 && s[0] == 0xFE && s[1] == 0xFF {
+		units := make([]uint16, 0, len(s)/2)
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+
+	var out strings.Builder
+	for _, b := range s {
+		switch {
+		case b >= 0x80 && b < 0xA0:
+			if r, ok := winAnsi[b]; ok {
+				out.WriteRune(r)
+			}
+		case b >= 0xA0:
+			out.WriteRune(rune(b))
+		case b < 0x20:
+			// TeX fonts put ligatures in the control range
+			if ligature, ok := texLigatures[b]; ok {
+				out.WriteString(ligature)
+			} else if b == '\t' {
+				out.WriteByte(' ')
+			}
+		default:
+			out.WriteByte(b)
+		}
+	}
+	return out.String()
+}
+
+// winAnsi maps the 0x80–0x9F range of WinAnsiEncoding
+var winAnsi = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡',
+	0x88: 'ˆ', 0x89: '‰', 0x8A: 'Š', 0x8B: '‹', 0x8C: 'Œ', 0x8E: 'Ž',
+	0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—',
+	0x98: '˜', 0x99: '™', 0x9A: 'š', 0x9B: '›', 0x9C: 'œ', 0x9E: 'ž', 0x9F: 'Ÿ',
+}
+
+// texLigatures maps the ligature slots of TeX's OT1 encoding
+var texLigatures = map[byte]string{
+	0x0B: "ff", 0x0C: "fi", 0x0D: "fl", 0x0E: "ffi", 0x0F: "ffl",
+}
+
+// printable reports whether most of the text is readable, to drop the
+// output of fonts whose codes are glyph IDs rather than characters
+func printable(text string) bool {
+	if text == "" {
+		return false
+	}
+	good, total := 0, 0
+	for _, r := range text {
+		total++
+		if r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || unicode.IsPunct(r)) {
+			good++
+		}
+	}
+	return good*4 >= total*3
+}
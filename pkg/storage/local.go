@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// Local stores objects as files under a root directory
+type Local struct {
+	root string
+}
+
+// NewLocal creates a local filesystem backend rooted at the given directory
+func NewLocal(root string) *Local {
+	return &Local{root: root}
+}
+
+// Put writes the object to a temporary file and renames it into place, so
+// readers never see a partial file
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	target := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".put-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// Get opens the file of an object
+func (l *Local) Get(ctx context.Context, key string) (Object, error) {
+	file, err := os.Open(l.path(key))
+	if err != nil {
+		return nil, notExist(err)
+	}
+
+	info, err := l.info(key, file.Stat)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &localObject{File: file, info: info}, nil
+}
+
+// Stat describes the file of an object
+func (l *Local) Stat(ctx context.Context, key string) (Info, error) {
+	return l.info(key, func() (os.FileInfo, error) {
+		return os.Stat(l.path(key))
+	})
+}
+
+// Delete removes the file of an object
+func (l *Local) Delete(ctx context.Context, key string) error {
+	if err := os.Remove(l.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// PresignGet is not supported; files on local disk are served by the application
+func (l *Local) PresignGet(ctx context.Context, key string, expires time.Duration, headers ResponseHeaders) (string, error) {
+	return "", ErrPresignUnsupported
+}
+
+func (l *Local) info(key string, stat func() (os.FileInfo, error)) (Info, error) {
+	fi, err := stat()
+	if err != nil {
+		return Info{}, notExist(err)
+	}
+	if fi.IsDir() {
+		return Info{}, ErrNotExist
+	}
+	return Info{
+		Key:         key,
+		Size:        fi.Size(),
+		ModTime:     fi.ModTime(),
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+	}, nil
+}
+
+// path resolves a key inside the root, never outside it
+func (l *Local) path(key string) string {
+	return filepath.Join(l.root, filepath.FromSlash(path.Clean("/"+key)))
+}
+
+func notExist(err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotExist
+	}
+	return err
+}
+
+type localObject struct {
+	*os.File
+	info Info
+}
+
+func (o *localObject) Info() Info {
+	return o.info
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocal(t *testing.T) {
+	root := t.TempDir()
+	l := NewLocal(root)
+	ctx := context.Background()
+	content := []byte("%PDF-1.7 content")
+
+	if err := l.Put(ctx, "pdf/3a/x.pdf", bytes.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "pdf", "3a", "x.pdf")); err != nil {
+		t.Errorf("file not written under the root: %v", err)
+	}
+
+	info, err := l.Stat(ctx, "pdf/3a/x.pdf")
+	if err != nil || info.Size != int64(len(content)) || info.ContentType != "application/pdf" {
+		t.Errorf("Stat = %+v, %v", info, err)
+	}
+
+	object, err := l.Get(ctx, "pdf/3a/x.pdf")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	object.Seek(9, io.SeekStart)
+	rest, _ := io.ReadAll(object)
+	object.Close()
+	if string(rest) != "content" {
+		t.Errorf("read after seek = %q", rest)
+	}
+
+	if _, err := l.Stat(ctx, "pdf/3a"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Stat of a directory = %v, want ErrNotExist", err)
+	}
+	if _, err := l.PresignGet(ctx, "pdf/3a/x.pdf", time.Minute, ResponseHeaders{}); !errors.Is(err, ErrPresignUnsupported) {
+		t.Errorf("PresignGet = %v, want ErrPresignUnsupported", err)
+	}
+
+	if err := l.Delete(ctx, "pdf/3a/x.pdf"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := l.Get(ctx, "pdf/3a/x.pdf"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Get after Delete = %v, want ErrNotExist", err)
+	}
+	if err := l.Delete(ctx, "pdf/3a/x.pdf"); err != nil {
+		t.Errorf("Delete of a missing object = %v", err)
+	}
+}
+
+func TestLocalKeysStayInRoot(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "media")
+	l := NewLocal(root)
+	ctx := context.Background()
+
+	for _, key := range []string{"../x", "../../x", "/../x", "a/../../x"} {
+		if err := l.Put(ctx, key, strings.NewReader(key), int64(len(key)), ""); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+		if _, err := os.Stat(filepath.Join(parent, "x")); err == nil {
+			t.Fatalf("Put(%q) wrote outside the root", key)
+		}
+		if _, err := os.Stat(filepath.Join(root, "x")); err != nil {
+			t.Errorf("Put(%q) did not write inside the root: %v", key, err)
+		}
+	}
+
+	// A file next to the root must not be reachable either
+	if err := os.WriteFile(filepath.Join(parent, "secret"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Get(ctx, "../secret"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Get(../secret) = %v, want ErrNotExist", err)
+	}
+	if err := l.Delete(ctx, "../secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(parent, "secret")); err != nil {
+		t.Errorf("Delete(../secret) removed a file outside the root")
+	}
+}
@@ -0,0 +1,82 @@
+// Package storage keeps media files in a pluggable backend: the local
+// filesystem or an S3-compatible object store. Objects are addressed by
+// slash-separated keys such as pdf/3a/3a7b….pdf.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"freescholar-backend/config"
+)
+
+// Drivers selectable in config.MediaConfig
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+var (
+	// ErrNotExist is returned for keys that hold no object
+	ErrNotExist = errors.New("storage: object does not exist")
+	// ErrPresignUnsupported is returned by drivers that cannot hand out URLs
+	ErrPresignUnsupported = errors.New("storage: driver does not support presigned URLs")
+)
+
+// Info describes a stored object
+type Info struct {
+	Key         string
+	Size        int64
+	ModTime     time.Time
+	ContentType string
+	ETag        string
+}
+
+// Object is an open stored object. Seeking is cheap, so objects can be
+// passed to http.ServeContent to answer Range requests.
+type Object interface {
+	io.ReadSeekCloser
+	Info() Info
+}
+
+// ResponseHeaders override headers of the response to a presigned URL
+type ResponseHeaders struct {
+	ContentType        string
+	ContentDisposition string
+}
+
+// Storage is a backend for media files
+type Storage interface {
+	// Put stores size bytes from r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens an object for reading
+	Get(ctx context.Context, key string) (Object, error)
+	// Stat describes an object without reading it
+	Stat(ctx context.Context, key string) (Info, error)
+	// Delete removes an object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// PresignGet returns a URL that downloads the object without further
+	// authentication until it expires
+	PresignGet(ctx context.Context, key string, expires time.Duration, headers ResponseHeaders) (string, error)
+}
+
+// New creates the storage backend selected by the media configuration
+func New(cfg config.MediaConfig) (Storage, error) {
+	switch cfg.Driver {
+	case DriverLocal, "":
+		return NewLocal(cfg.Root), nil
+	case DriverS3:
+		return NewS3(S3Options{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+		})
+	default:
+		return nil, fmt.Errorf("unknown media driver %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"freescholar-backend/config"
+	"freescholar-backend/internal/indexer"
+	"freescholar-backend/pkg/elasticsearch"
+	"freescholar-backend/pkg/mysql"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// runReindex rebuilds an Elasticsearch index from MySQL.
+// Usage: freescholar-backend reindex [-index publications] [-state reindex.state.json] [-resume] [-keep-old]
+func runReindex(args []string) {
+	flags := flag.NewFlagSet("reindex", flag.ExitOnError)
+	index := flags.String("index", "publications", "index to rebuild: publications or authors")
+	batchSize := flags.Int("batch", 1000, "number of publications per bulk request")
+	statePath := flags.String("state", "reindex.state.json", "file used to checkpoint progress")
+	resume := flags.Bool("resume", false, "continue an interrupted reindex from the state file")
+	keepOld := flags.Bool("keep-old", false, "keep the previous index after swapping the alias")
+	flags.Parse(args)
+
+	// Load configuration
+	cfg, err := config.LoadConfig("./config.yaml", "./secrets.json")
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Set up MySQL connection with GORM
+	db, err := mysql.NewClient(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to MySQL: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer sqlDB.Close()
+
+	// Set up Elasticsearch connection
+	esClient, err := elasticsearch.NewClient(cfg.ES)
+	if err != nil {
+		log.Fatalf("Failed to connect to Elasticsearch: %v", err)
+	}
+
+	// Stop after the current batch on interrupt; the state file allows resuming
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	reindexer, err := indexer.NewReindexer(db, esClient, indexer.ReindexOptions{
+		Index:     *index,
+		BatchSize: *batchSize,
+		StatePath: *statePath,
+		Resume:    *resume,
+		KeepOld:   *keepOld,
+		Retention: indexer.OutboxRetention(cfg.ES),
+		Out:       os.Stdout,
+	})
+	if err != nil {
+		log.Fatalf("Failed to set up reindex: %v", err)
+	}
+
+	if err := reindexer.Run(ctx); err != nil {
+		if ctx.Err() != nil {
+			log.Fatalf("Reindex interrupted; run again with -resume to continue")
+		}
+		log.Fatalf("Reindex failed: %v", err)
+	}
+}